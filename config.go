@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/gdamore/tcell"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// Config is the player's preferences, kept in config.json next to
+// the save file. Unlike the save and stats files, it is meant to be
+// edited by hand, so missing fields get their defaults.
+type Config struct {
+	Theme    string              `json:"theme,omitempty"`    // the name of the theme, or "" for the default
+	Keys     string              `json:"keys,omitempty"`     // the name of the keymap, or "" for the default
+	Bindings map[string][]string `json:"bindings,omitempty"` // keys for actions, in place of the keymap's
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Loading and saving
+///////////////////////////////////////////////////////////////////////////////
+
+// ConfigFilePath returns the location of the config file.
+func ConfigFilePath() (string, error) {
+	return ConfigPath("config.json")
+}
+
+// LoadConfig reads the config file. A missing config file has the
+// default settings.
+func LoadConfig() (Config, error) {
+	var config Config
+	path, err := ConfigFilePath()
+	if err != nil {
+		return config, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, errors.New("config file is not valid JSON: " + err.Error())
+	}
+	if _, ok := FindTheme(config.Theme); config.Theme != "" && !ok {
+		return config, errors.New("config file has an unknown theme " + config.Theme)
+	}
+	if _, err := MakeKeymap(config.Keys, config.Bindings); err != nil {
+		return config, errors.New("config file has unknown keys: " + err.Error())
+	}
+	return config, nil
+}
+
+// SaveConfig writes config to the config file.
+func SaveConfig(config Config) error {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+	return WriteConfigFile(path, data)
+}
+
+// SwitchTheme draws everything on s with the theme after the one
+// called name from now on, and saves it in the config file as the
+// player's choice. Returns an error if it couldn't be saved.
+func SwitchTheme(s tcell.Screen, name string) error {
+	UseTheme(s, NextThemeName(name))
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	config.Theme = CurrentTheme.name
+	return SaveConfig(config)
+}
+
+// SwitchKeys plays the game with the keymap after the one called
+// name from now on, along with the player's own bindings, and saves
+// it in the config file as the player's choice. Returns an error if
+// the config file couldn't be read or saved.
+func SwitchKeys(name string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	config.Keys = NextKeymapName(name)
+	keymap, err := MakeKeymap(config.Keys, config.Bindings)
+	if err != nil {
+		return err
+	}
+	CurrentKeys = keymap
+	return SaveConfig(config)
+}
@@ -0,0 +1,149 @@
+// Package engine has the rules of Spider Solitaire, without any
+// way of showing a game. It can be used to play games, check moves,
+// look for hints and solve deals.
+package engine
+
+///////////////////////////////////////////////////////////////////////////////
+// Data
+///////////////////////////////////////////////////////////////////////////////
+
+// CardValue is an enum denoting the different possible
+// values of a playing card.
+type CardValue int
+
+const (
+	NoneValue CardValue = iota
+	Ace
+	Two
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+)
+
+// This function is a workaround to get a constant global array
+func getValueToString() []string {
+	return []string{"None", "Ace", "Two", "Three", "Four", "Five",
+		"Six", "Seven", "Eight", "Nine", "Ten", "Jack", "Queen", "King"}
+}
+
+// This function is a workaround to get a constant global array
+func getValueToLabel() []string {
+	return []string{"?", "A", "2", "3", "4", "5",
+		"6", "7", "8", "9", "10", "J", "Q", "K"}
+}
+
+// NUM_VALUES is the number of different possible values
+// (not including NoneValue).
+var NUM_VALUES = int(King)
+
+// CardSuit is an enum denoting the possible suits of a playing card.
+type CardSuit int
+
+const (
+	NoneSuit CardSuit = iota
+	Spades
+	Hearts
+	Clubs
+	Diamonds
+)
+
+// This function is a workaround to get a global constant array
+func getSuitToString() []string {
+	return []string{"None", "Spades", "Hearts", "Clubs", "Diamonds"}
+}
+
+// This function is a workaround to get a global constant array
+func getSuitToSymbol() []rune {
+	return []rune{'?', '♠', '♥', '♣', '♦'}
+}
+
+// This function is a workaround to get a global constant array
+func getSuitToLetter() []rune {
+	return []rune{'?', 'S', 'H', 'C', 'D'}
+}
+
+// Card represents a single playing card.
+type Card struct {
+	suit  CardSuit
+	value CardValue
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Card Functions
+///////////////////////////////////////////////////////////////////////////////
+
+// NewCard returns the card with value of suit.
+func NewCard(suit CardSuit, value CardValue) Card {
+	return Card{suit, value}
+}
+
+// Suit returns the suit of card.
+func (card Card) Suit() CardSuit {
+	return card.suit
+}
+
+// Value returns the value of card.
+func (card Card) Value() CardValue {
+	return card.value
+}
+
+// IsValid returns true iff card is one of the 52 playing cards.
+func (card Card) IsValid() bool {
+	return card.suit >= Spades && card.suit <= Diamonds &&
+		card.value >= Ace && card.value <= King
+}
+
+// ToString returns the name of card, such as "Queen of Hearts".
+func (card Card) ToString() string {
+	return getValueToString()[card.value] +
+		" of " + getSuitToString()[card.suit]
+}
+
+// ToString returns the name of cv, such as "Queen".
+func (cv CardValue) ToString() string {
+	return getValueToString()[cv]
+}
+
+// ToString returns the name of suit, such as "Hearts".
+func (suit CardSuit) ToString() string {
+	return getSuitToString()[suit]
+}
+
+// Label returns the short name of cv shown in the corner of a
+// card, such as "Q" or "10".
+func (cv CardValue) Label() string {
+	return getValueToLabel()[cv]
+}
+
+// Symbol returns the symbol of suit, such as '♥'.
+func (suit CardSuit) Symbol() rune {
+	return getSuitToSymbol()[suit]
+}
+
+// Letter returns the first letter of the name of suit, such as 'H',
+// for where its symbol can't be shown.
+func (suit CardSuit) Letter() rune {
+	return getSuitToLetter()[suit]
+}
+
+// IsRed returns true iff suit is one of the red suits, Hearts and
+// Diamonds.
+func (suit CardSuit) IsRed() bool {
+	return suit == Hearts || suit == Diamonds
+}
+
+// isBlank returns true iff the card has the NoneSuit and NoneValue.
+func (card Card) isBlank() bool {
+	if card.suit == NoneSuit || card.value == NoneValue {
+		return true
+	}
+	return false
+}
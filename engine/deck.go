@@ -1,14 +1,15 @@
-package main
+package engine
 
 import (
-	"log"
+	"errors"
 	"math/rand"
-	"time"
-
-	"github.com/gdamore/tcell"
 	// "fmt"
 )
 
+// ErrNotEnoughCards is returned when more cards are asked for than
+// there are in a Deck.
+var ErrNotEnoughCards = errors.New("asking for more cards than exist")
+
 ///////////////////////////////////////////////////////////////////////////////
 // Data Types
 ///////////////////////////////////////////////////////////////////////////////
@@ -54,28 +55,28 @@ func (deck *Deck) Draw() Card {
 
 // PeekTopNCards returns a slice of the top n cards of Deck deck..
 // The Cards are not removed from deck.
-func (deck Deck) PeekTopNCards(n int) []Card {
+func (deck Deck) PeekTopNCards(n int) ([]Card, error) {
 	length := len(deck.cards)
-	if length < n {
-		log.Fatalf("Asking for more cards than exist")
+	if length < n || n < 0 {
+		return nil, ErrNotEnoughCards
 	}
-	return deck.cards[length-n:]
+	return deck.cards[length-n:], nil
 }
 
 // GetTopNCards returns a slice of a new array containing
 // a copy of the top n cards of Deck deck.
 // The Cards are removed from deck.
-func (deck *Deck) GetTopNCards(n int) []Card {
+func (deck *Deck) GetTopNCards(n int) ([]Card, error) {
 	length := len(deck.cards)
-	if length < n {
-		log.Fatalf("Asking for more cards than exist")
+	if length < n || n < 0 {
+		return nil, ErrNotEnoughCards
 	}
 	topN := deck.cards[length-n:]
 	newTopN := make([]Card, len(topN))
 	copy(newTopN, topN)
 
 	deck.cards = deck.cards[:length-n]
-	return newTopN
+	return newTopN, nil
 }
 
 // PeekNthCard returns the nth Card from the top of deck.
@@ -98,15 +99,30 @@ func NewDeck(initSize int) Deck {
 	return deck
 }
 
+// Cards returns a copy of the cards in deck, from the bottom of the
+// deck to the top.
+func (deck Deck) Cards() []Card {
+	return append([]Card(nil), deck.cards...)
+}
+
+// Copy returns a new Deck with the same cards as deck, which
+// does not share its underlying array with deck.
+func (deck Deck) Copy() Deck {
+	var newDeck Deck = NewDeck(len(deck.cards))
+	newDeck.cards = append(newDeck.cards, deck.cards...)
+	return newDeck
+}
+
 // Swap swaps the cards in the indices fst and snd or the cards array.
 func (deck *Deck) Swap(fst int, snd int) {
 	deck.cards[fst], deck.cards[snd] = deck.cards[snd], deck.cards[fst]
 }
 
-// Shuffle randomizes the order of deck.
-func (deck *Deck) Shuffle() {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(deck.cards), deck.Swap)
+// Shuffle randomizes the order of deck using rng. Shuffling
+// the same cards with an rng made from the same seed always
+// gives the same order.
+func (deck *Deck) Shuffle(rng *rand.Rand) {
+	rng.Shuffle(len(deck.cards), deck.Swap)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -120,26 +136,8 @@ func (deck *Deck) ToString() string {
 	}
 	var str string = ""
 	for _, v := range deck.cards {
-		str += v.toString() + ", "
+		str += v.ToString() + ", "
 	}
 	str = str[:len(str)-1]
 	return str
 }
-
-///////////////////////////////////////////////////////////////////////////////
-// Graphics Function
-///////////////////////////////////////////////////////////////////////////////
-
-// Render renders a deck of cards face side up
-func (deck Deck) Render(s tcell.Screen, x int, y int) {
-	for i, v := range deck.cards {
-		v.Render(s, x, y+2*i)
-	}
-}
-
-// RenderFlipped renders a deck of cards face side down
-func (deck Deck) RenderFlipped(s tcell.Screen, x int, y int) {
-	for i, v := range deck.cards {
-		v.RenderFlipped(s, x, y+i)
-	}
-}
@@ -0,0 +1,464 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+const NUM_PILES = 10
+const NUM_CARDS = 104
+
+// NUM_STACKS is the number of full stacks there are in a game.
+const NUM_STACKS = NUM_CARDS / int(King)
+
+// START_SCORE is the score at the start of a game, and STACK_SCORE
+// is the score for removing a full stack.
+const START_SCORE = 500
+const STACK_SCORE = 100
+
+// MAX_RANDOM_SEED is one more than the largest deal number picked at
+// random, so that random deal numbers are short enough to share.
+const MAX_RANDOM_SEED = 1000000000
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// Game is the state of the cards of one game of Spider Solitaire,
+// along with everything that has been done to them.
+type Game struct {
+	deck       Deck // The remaining deck which has cards not yet on piles
+	piles      [NUM_PILES]Pile
+	foundation []CardSuit // the suit of each full stack removed, oldest first
+	difficulty Difficulty // how many suits the game is played with
+	seed       int64      // the deal number the deck was shuffled with
+	rules      Rules
+	moves      int        // how many moves and deals the player has made
+	undos      int        // how many times the player has undone an action
+	undo       []snapshot // the cards before each action, most recent last
+	history    []Action   // everything the player has done, oldest first
+	redo       []snapshot // the cards before each undone action, most recent last
+}
+
+// Rules are the choices of how a game is played which are not part
+// of the deal.
+type Rules struct {
+	// Relaxed allows cards to be dealt while a pile is empty.
+	Relaxed bool
+	// ManualCollect leaves full stacks on the piles until they are
+	// collected with Collect.
+	ManualCollect bool
+}
+
+// Difficulty is the number of different suits the cards
+// of a game are drawn from. Fewer suits is easier.
+type Difficulty int
+
+const (
+	OneSuit   Difficulty = 1
+	TwoSuits  Difficulty = 2
+	FourSuits Difficulty = 4
+)
+
+// ErrStockEmpty and ErrPileEmpty are the reasons that cards can't
+// be dealt from the deck.
+var ErrStockEmpty = errors.New("the stock is empty")
+var ErrPileEmpty = errors.New("a pile is empty")
+
+// ErrNoSuchPile and ErrIllegalMove are the reasons that cards can't
+// be moved.
+var ErrNoSuchPile = errors.New("there is no such pile")
+var ErrIllegalMove = errors.New("those cards can't be moved there")
+
+///////////////////////////////////////////////////////////////////////////////
+// Setting up a game
+///////////////////////////////////////////////////////////////////////////////
+
+// CreateDeck creates the deck with all cards. The Deck
+// always has as many cards as 2 full standard playing card
+// decks (without jokers), but only uses the suits allowed
+// by difficulty. For example, a OneSuit deck is 8 full
+// runs of Spades. The deck is shuffled into the order
+// given by seed.
+func CreateDeck(difficulty Difficulty, seed int64) Deck {
+	var deck Deck = NewDeck(NUM_CARDS)
+	suits := difficulty.Suits()
+	for i := 0; i < NUM_CARDS/(len(suits)*NUM_VALUES); i++ {
+		for _, s := range suits {
+			for v := Ace; v <= King; v++ {
+				deck.Add(Card{s, v})
+			}
+		}
+	}
+	deck.Shuffle(rand.New(rand.NewSource(seed)))
+	return deck
+}
+
+// NewSeed picks a random deal number.
+func NewSeed() int64 {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return rng.Int63n(MAX_RANDOM_SEED) + 1
+}
+
+// NewGame deals the game of deal number seed at difficulty, to be
+// played with rules.
+func NewGame(difficulty Difficulty, seed int64, rules Rules) Game {
+	var game Game
+	var deck Deck = CreateDeck(difficulty, seed)
+	// first four piles get 6 cards
+	for p := 0; p < 4; p++ {
+		for c := 0; c < 5; c++ {
+			var card Card = deck.Draw()
+			game.piles[p].invisible.Add(card)
+		}
+	}
+	// last for get 5 cards
+	for p := 4; p < NUM_PILES; p++ {
+		for c := 0; c < 4; c++ {
+			var card Card = deck.Draw()
+			game.piles[p].invisible.Add(card)
+		}
+	}
+	// the top card is visible
+	for p := 0; p < NUM_PILES; p++ {
+		var card Card = deck.Draw()
+		game.piles[p].visible.Add(card)
+	}
+
+	game.deck = deck
+	game.difficulty = difficulty
+	game.seed = seed
+	game.rules = rules
+	return game
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// State queries
+///////////////////////////////////////////////////////////////////////////////
+
+// Stock returns a copy of the cards which have not been dealt yet.
+func (game Game) Stock() Deck {
+	return game.deck.Copy()
+}
+
+// Pile returns a copy of pile i.
+func (game Game) Pile(i int) Pile {
+	return game.piles[i].Copy()
+}
+
+// Foundation returns the suit of each full stack removed from the
+// piles, oldest first.
+func (game Game) Foundation() []CardSuit {
+	return append([]CardSuit(nil), game.foundation...)
+}
+
+// Difficulty returns how many suits game is played with.
+func (game Game) Difficulty() Difficulty {
+	return game.difficulty
+}
+
+// Seed returns the deal number of game.
+func (game Game) Seed() int64 {
+	return game.seed
+}
+
+// Rules returns the rules game is played with.
+func (game Game) Rules() Rules {
+	return game.rules
+}
+
+// Moves returns how many moves and deals have been made.
+func (game Game) Moves() int {
+	return game.moves
+}
+
+// Undos returns how many actions have been undone.
+func (game Game) Undos() int {
+	return game.undos
+}
+
+// DealsLeft returns how many more times cards can be dealt from the
+// deck, counting a last partial row.
+func (game Game) DealsLeft() int {
+	return (game.deck.Size() + NUM_PILES - 1) / NUM_PILES
+}
+
+// CompletedStacks returns how many full stacks have been removed
+// from the game.
+func (game Game) CompletedStacks() int {
+	return len(game.foundation)
+}
+
+// Score returns the player's score, which is scored like classic
+// Windows Spider Solitaire. The player starts with 500 points, loses
+// one for every move, deal and undo, and gains 100 for every full
+// stack removed.
+func (game Game) Score() int {
+	return START_SCORE - game.moves - game.undos +
+		STACK_SCORE*game.CompletedStacks()
+}
+
+// CheckWon checks if there are no more cards and so the user
+// has won. Returns true is the user has won, and false otherwise.
+func (game Game) CheckWon() bool {
+	for i := 0; i < NUM_PILES; i++ {
+		if !game.piles[i].IsEmpty() {
+			return false
+		}
+	}
+	// if theres nothing more in the deck or the piles, then we won
+	return game.deck.IsEmpty()
+}
+
+// CanAutoFinish returns true if every card has been dealt and turned
+// face up, so that the rest of the game can be played without any
+// more cards being revealed.
+func (game Game) CanAutoFinish() bool {
+	if !game.deck.IsEmpty() {
+		return false
+	}
+	for i := 0; i < NUM_PILES; i++ {
+		if !game.piles[i].invisible.IsEmpty() {
+			return false
+		}
+	}
+	return !game.CheckWon()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Playing
+///////////////////////////////////////////////////////////////////////////////
+
+// Apply makes move, which is either a deal or a move of cards between
+// piles. Returns the reason if move can't be made.
+func (game *Game) Apply(move Move) error {
+	if move.Deal {
+		return game.Deal()
+	}
+	return game.MoveRun(move.From, move.Count, move.To)
+}
+
+// ApplySolved makes move, one of the moves of a Solution, and then
+// collects any full stacks it made even if the rules have the player
+// collect them by hand, since the solver always collects them at
+// once. Returns the reason if move can't be made.
+func (game *Game) ApplySolved(move Move) error {
+	if err := game.Apply(move); err != nil {
+		return err
+	}
+	if game.rules.ManualCollect {
+		game.Collect()
+	}
+	return nil
+}
+
+// CanDeal returns the reason that cards can't be dealt from the deck,
+// or nil if they can. Unless the rules are relaxed, cards can't be
+// dealt while any pile is empty.
+func (game Game) CanDeal() error {
+	if game.deck.IsEmpty() {
+		return ErrStockEmpty
+	}
+	if !game.rules.Relaxed {
+		for i := 0; i < NUM_PILES; i++ {
+			if game.piles[i].IsEmpty() {
+				return ErrPileEmpty
+			}
+		}
+	}
+	return nil
+}
+
+// Deal deals another layer of cards onto the piles from the
+// deck. Returns the reason if the cards can't be dealt.
+func (game *Game) Deal() error {
+	if err := game.CanDeal(); err != nil {
+		return err
+	}
+	before := game.takeSnapshot()
+	game.dealRow()
+	game.saveUndo(before)
+	game.moves++
+	game.record(Action{Kind: ActionDeal})
+	game.autoCollect()
+	return nil
+}
+
+// dealRow deals one card from the deck onto each pile, starting from
+// the left. If the deck has fewer cards than there are piles, the
+// piles on the right get no card.
+func (game *Game) dealRow() {
+	for i := 0; i < NUM_PILES && !game.deck.IsEmpty(); i++ {
+		game.piles[i].visible.Add(game.deck.Draw())
+	}
+}
+
+// MoveRun moves the top n cards of pile from onto pile to. Returns
+// the reason if the cards can't be moved.
+func (game *Game) MoveRun(from int, n int, to int) error {
+	if from < 0 || from >= NUM_PILES || to < 0 || to >= NUM_PILES {
+		return ErrNoSuchPile
+	}
+	if !game.CanMoveRun(from, n, to) {
+		return ErrIllegalMove
+	}
+	before := game.takeSnapshot()
+	if err := game.moveRun(from, n, to); err != nil {
+		// Leave the cards as they were, with nothing to undo.
+		game.restore(before)
+		return err
+	}
+	game.saveUndo(before)
+	game.moves++
+	game.record(Action{Kind: ActionMove, Move: Move{From: from, Count: n, To: to}})
+	game.autoCollect()
+	return nil
+}
+
+// moveRun moves the top n cards of pile from onto pile to without
+// checking that the move is allowed.
+func (game *Game) moveRun(from int, n int, to int) error {
+	topNCards, err := game.piles[from].GetTopNCards(n)
+	if err != nil {
+		return err
+	}
+	for _, v := range topNCards {
+		game.piles[to].visible.Add(v)
+	}
+	return nil
+}
+
+// CanMoveRun returns true if the top n cards of pile from can be
+// moved onto pile to. The cards must be movable together (see
+// TopNMovable), and the top moved card must be one lower in value
+// than the card it lands on, unless pile to is empty. Returns false
+// if either pile doesn't exist.
+func (game Game) CanMoveRun(from int, n int, to int) bool {
+	if from < 0 || from >= NUM_PILES || to < 0 || to >= NUM_PILES {
+		return false
+	}
+	if n < 1 || from == to || !game.piles[from].TopNMovable(n) {
+		return false
+	}
+	var topMovedCard Card = game.piles[from].PeekNthCard(n - 1)
+	var cardMovedOnto Card = game.piles[to].PeekNthCard(0)
+	return topMovedCard.value == cardMovedOnto.value-1 ||
+		game.piles[to].IsEmpty()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Full stacks
+///////////////////////////////////////////////////////////////////////////////
+
+// IsFullStack returns true if the top 13 cards are a full stack,
+// that is King down to Ace all of the same suit.
+func IsFullStack(cards []Card) bool {
+	if len(cards) < NUM_VALUES {
+		//fmt.Println("length: ", len(cards), NUM_VALUES)
+		return false
+	}
+
+	stopVal := 0
+	if len(cards)-NUM_VALUES > 0 {
+		stopVal = len(cards) - NUM_VALUES
+	}
+
+	suit := cards[len(cards)-1].suit
+	currValue := Ace
+	for i := len(cards) - 1; i >= stopVal; i-- {
+
+		if cards[i].value != currValue || cards[i].suit != suit {
+			return false
+		}
+		currValue++
+	}
+	return true
+}
+
+// checkStacks looks for piles that contain a full stack of
+// cards, and moves the full stacks to the foundation. Returns
+// the piles that full stacks were moved from.
+func (game *Game) checkStacks() []int {
+	var cleared []int
+	for i := 0; i < NUM_PILES; i++ {
+		if IsFullStack(game.piles[i].visible.cards) {
+			suit := game.piles[i].PeekNthCard(0).suit
+			if _, err := game.piles[i].GetTopNCards(NUM_VALUES); err != nil {
+				continue
+			}
+			game.foundation = append(game.foundation, suit)
+			cleared = append(cleared, i)
+		}
+	}
+	return cleared
+}
+
+// autoCollect removes any full stacks from the piles after an
+// action, unless they are collected by hand.
+func (game *Game) autoCollect() {
+	if !game.rules.ManualCollect {
+		game.recordCleared(game.checkStacks())
+	}
+}
+
+// Collect removes every full stack from the piles, as an action of
+// its own. Returns false if there were no full stacks.
+func (game *Game) Collect() bool {
+	if !game.HasFullStack() {
+		return false
+	}
+	before := game.takeSnapshot()
+	cleared := game.checkStacks()
+	if len(cleared) == 0 {
+		return false
+	}
+	game.saveUndo(before)
+	game.record(Action{Kind: ActionCollect, Cleared: cleared})
+	return true
+}
+
+// HasFullStack returns true if any pile ends in a full stack that
+// could be collected.
+func (game Game) HasFullStack() bool {
+	for i := 0; i < NUM_PILES; i++ {
+		if IsFullStack(game.piles[i].visible.cards) {
+			return true
+		}
+	}
+	return false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Difficulty functions
+///////////////////////////////////////////////////////////////////////////////
+
+// Suits returns the suits the cards are drawn from at this difficulty.
+func (difficulty Difficulty) Suits() []CardSuit {
+	switch difficulty {
+	case OneSuit:
+		return []CardSuit{Spades}
+	case TwoSuits:
+		return []CardSuit{Spades, Hearts}
+	default:
+		return []CardSuit{Spades, Hearts, Clubs, Diamonds}
+	}
+}
+
+// IsValid returns true iff difficulty is one of the supported modes.
+func (difficulty Difficulty) IsValid() bool {
+	return difficulty == OneSuit || difficulty == TwoSuits ||
+		difficulty == FourSuits
+}
+
+// ToString returns the name of difficulty, such as "Two suits".
+func (difficulty Difficulty) ToString() string {
+	switch difficulty {
+	case OneSuit:
+		return "One suit"
+	case TwoSuits:
+		return "Two suits"
+	default:
+		return "Four suits"
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import "testing"
+
+func TestCanMoveRun(t *testing.T) {
+	mixed := []Card{{Spades, Five}, {Hearts, Four}, {Spades, Three}}
+	tests := []struct {
+		name string
+		from []Card
+		n    int
+		to   []Card
+		want bool
+	}{
+		{"same-suit run onto the next card up", descending(Spades, Five, Three), 3, []Card{{Hearts, Six}}, true},
+		{"same-suit run onto the wrong card", descending(Spades, Five, Three), 3, []Card{{Hearts, Seven}}, false},
+		{"mixed-suit run in order", mixed, 3, []Card{{Hearts, Six}}, false},
+		{"top card of a mixed-suit run", mixed, 1, []Card{{Hearts, Four}}, true},
+		{"single card onto the next card up", []Card{{Clubs, Nine}}, 1, []Card{{Spades, Ten}}, true},
+		{"single card onto the wrong card", []Card{{Clubs, Nine}}, 1, []Card{{Spades, Nine}}, false},
+		{"single card onto an empty pile", []Card{{Clubs, Nine}}, 1, nil, true},
+		{"mixed-suit run onto an empty pile", mixed, 3, nil, false},
+	}
+	for _, test := range tests {
+		var game Game
+		game.piles[0] = faceUp(test.from...)
+		game.piles[1] = faceUp(test.to...)
+		if got := game.CanMoveRun(0, test.n, 1); got != test.want {
+			t.Errorf("%s: CanMoveRun(0, %d, 1) = %v, want %v", test.name, test.n, got, test.want)
+		}
+	}
+}
+
+func TestCanMoveRunNoSuchPile(t *testing.T) {
+	var game Game
+	game.piles[0] = faceUp(Card{Clubs, Nine})
+	for _, piles := range [][2]int{{0, NUM_PILES}, {0, -1}, {NUM_PILES, 0}, {-1, 0}} {
+		if game.CanMoveRun(piles[0], 1, piles[1]) {
+			t.Errorf("CanMoveRun(%d, 1, %d) = true, want false", piles[0], piles[1])
+		}
+	}
+}
+
+func TestIsFullStack(t *testing.T) {
+	mixed := descending(Spades, King, Ace)
+	mixed[6] = Card{Hearts, Seven}
+	tests := []struct {
+		name  string
+		cards []Card
+		want  bool
+	}{
+		{"same-suit King to Ace", descending(Spades, King, Ace), true},
+		{"same-suit King to Ace on other cards", append([]Card{{Hearts, Two}}, descending(Clubs, King, Ace)...), true},
+		{"mixed-suit King to Ace", mixed, false},
+		{"same-suit King to Two", descending(Spades, King, Two), false},
+		{"single card", []Card{{Spades, Ace}}, false},
+	}
+	for _, test := range tests {
+		if got := IsFullStack(test.cards); got != test.want {
+			t.Errorf("%s: IsFullStack = %v, want %v", test.name, got, test.want)
+		}
+		var game Game
+		game.piles[0] = faceUp(test.cards...)
+		cleared := game.checkStacks()
+		if got := len(cleared) == 1; got != test.want {
+			t.Errorf("%s: checkStacks cleared %v", test.name, cleared)
+		}
+	}
+}
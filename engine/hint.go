@@ -0,0 +1,69 @@
+package engine
+
+import "sort"
+
+///////////////////////////////////////////////////////////////////////////////
+// Hint functions
+///////////////////////////////////////////////////////////////////////////////
+
+// Hints returns every useful move in the game, best first.
+func (game Game) Hints() []Move {
+	legal := game.possibleMoves()
+	var scores = make(map[Move]int)
+	moves := make([]Move, len(legal))
+	for i, move := range legal {
+		scores[move.Move] = game.scoreMove(move)
+		moves[i] = move.Move
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+	return moves
+}
+
+// possibleMoves returns every useful move of cards between piles.
+// Moves that don't change anything, such as moving a whole pile
+// into an empty pile, are left out.
+func (game Game) possibleMoves() []LegalMove {
+	var moves []LegalMove
+	for _, move := range game.LegalMoves() {
+		if !move.Deal && game.isUseful(move) {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+// isUseful returns false for a move of cards between piles that
+// doesn't change anything.
+func (game Game) isUseful(move LegalMove) bool {
+	// Moving a whole pile only swaps which pile is empty.
+	return !move.EmptiesColumn || !game.piles[move.To].IsEmpty()
+}
+
+// scoreMove returns how good move is compared to other moves.
+// Building in suit is best, then moves that turn over a face down
+// card or empty a pile. Moves into an empty pile are only worth
+// it when they free up something.
+func (game Game) scoreMove(move LegalMove) int {
+	from := game.piles[move.From]
+	topMoved := from.PeekNthCard(move.Count - 1)
+	below := from.PeekNthCard(move.Count)
+	score := move.Count
+
+	if move.InSuit {
+		score += 100
+	}
+	if move.Reveals {
+		score += 50
+	} else if move.EmptiesColumn {
+		score += 30
+	} else if below.suit == topMoved.suit && below.value == topMoved.value+1 {
+		// This splits a run that was already in suit.
+		score -= 80
+	}
+	if game.piles[move.To].IsEmpty() {
+		score -= 20
+	}
+	return score
+}
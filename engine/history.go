@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HISTORY_VERSION is the version of the move history file format.
+const HISTORY_VERSION = 1
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// ActionKind is the kind of thing a player did.
+type ActionKind string
+
+const (
+	ActionMove    ActionKind = "move"
+	ActionDeal    ActionKind = "deal"
+	ActionUndo    ActionKind = "undo"
+	ActionRedo    ActionKind = "redo"
+	ActionCollect ActionKind = "collect"
+)
+
+// Action is one thing the player did that changed the cards.
+type Action struct {
+	Kind    ActionKind
+	Move    Move  // the cards moved, for ActionMove
+	Cleared []int // the piles a full stack was removed from afterwards
+}
+
+// History is everything needed to play a game again: the deal,
+// and every action taken, oldest first.
+type History struct {
+	Difficulty Difficulty
+	Seed       int64
+	Rules      Rules
+	Actions    []Action
+}
+
+// ToString describes action to the player.
+func (action Action) ToString() string {
+	text := string(action.Kind)
+	if action.Kind == ActionMove {
+		text = action.Move.ToString()
+	}
+	if len(action.Cleared) > 0 {
+		text += ", clearing a full stack"
+	}
+	return text
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Recording actions
+///////////////////////////////////////////////////////////////////////////////
+
+// record adds action to the end of the game's history.
+func (game *Game) record(action Action) {
+	game.history = append(game.history, action)
+}
+
+// recordCleared notes that full stacks were removed from the piles
+// in cleared because of the last action.
+func (game *Game) recordCleared(cleared []int) {
+	if len(cleared) == 0 || len(game.history) == 0 {
+		return
+	}
+	last := &game.history[len(game.history)-1]
+	last.Cleared = append(last.Cleared, cleared...)
+}
+
+// History returns the history of game so far.
+func (game Game) History() History {
+	return History{game.difficulty, game.seed, game.rules,
+		append([]Action(nil), game.history...)}
+}
+
+// ErrNothingToUndo, ErrNothingToRedo and ErrNothingToCollect are
+// the reasons that an undo, redo or collect action can't be done.
+var ErrNothingToUndo = errors.New("there is nothing to undo")
+var ErrNothingToRedo = errors.New("there is nothing to redo")
+var ErrNothingToCollect = errors.New("there are no full stacks to collect")
+
+// ApplyAction does action to game, in the same way as if the player
+// had done it. Returns the reason if action could not be done.
+func (game *Game) ApplyAction(action Action) error {
+	switch action.Kind {
+	case ActionMove:
+		return game.Apply(action.Move)
+	case ActionDeal:
+		return game.Deal()
+	case ActionUndo:
+		if !game.Undo() {
+			return ErrNothingToUndo
+		}
+	case ActionRedo:
+		if !game.Redo() {
+			return ErrNothingToRedo
+		}
+	case ActionCollect:
+		if !game.Collect() {
+			return ErrNothingToCollect
+		}
+	default:
+		return fmt.Errorf("unknown action %q", action.Kind)
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Reading and writing history files
+///////////////////////////////////////////////////////////////////////////////
+
+// Write writes history to w in the move history file format: a
+// header giving the deal, and then one line per action. Piles are
+// numbered from 1, as they are shown to the player.
+func (history History) Write(w io.Writer) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "# Spider Solitaire move history\n")
+	fmt.Fprintf(&text, "version %d\n", HISTORY_VERSION)
+	fmt.Fprintf(&text, "difficulty %d\n", history.Difficulty)
+	fmt.Fprintf(&text, "seed %d\n", history.Seed)
+	if history.Rules.Relaxed {
+		text.WriteString("relaxed\n")
+	}
+	if history.Rules.ManualCollect {
+		text.WriteString("manual-collect\n")
+	}
+	for _, action := range history.Actions {
+		text.WriteString(string(action.Kind))
+		if action.Kind == ActionMove {
+			fmt.Fprintf(&text, " %d %d %d", action.Move.From+1, action.Move.Count, action.Move.To+1)
+		}
+		if len(action.Cleared) > 0 {
+			text.WriteString(" cleared")
+			for _, pile := range action.Cleared {
+				fmt.Fprintf(&text, " %d", pile+1)
+			}
+		}
+		text.WriteString("\n")
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// ReadHistory reads a move history file written by History.Write.
+// Every action is checked by playing it, so that a history which
+// can't be replayed is rejected.
+func ReadHistory(r io.Reader) (History, error) {
+	var history History
+	var version int
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		lineErr := func(msg string) error {
+			return fmt.Errorf("line %d: %s", lineNum, msg)
+		}
+		numbers, err := parseNumbers(fields[1:])
+		if err != nil {
+			return history, lineErr(err.Error())
+		}
+
+		switch fields[0] {
+		case "version":
+			if len(numbers) != 1 || numbers[0] != HISTORY_VERSION {
+				return history, lineErr(fmt.Sprintf("only version %d is supported", HISTORY_VERSION))
+			}
+			version = int(numbers[0])
+		case "difficulty":
+			if len(numbers) != 1 || !Difficulty(numbers[0]).IsValid() {
+				return history, lineErr("difficulty must be 1, 2 or 4")
+			}
+			history.Difficulty = Difficulty(numbers[0])
+		case "seed":
+			if len(numbers) != 1 || numbers[0] <= 0 {
+				return history, lineErr("seed must be a positive number")
+			}
+			history.Seed = numbers[0]
+		case "relaxed":
+			if len(numbers) != 0 {
+				return history, lineErr("relaxed takes no numbers")
+			}
+			history.Rules.Relaxed = true
+		case "manual-collect":
+			if len(numbers) != 0 {
+				return history, lineErr("manual-collect takes no numbers")
+			}
+			history.Rules.ManualCollect = true
+		case string(ActionMove):
+			if len(numbers) < 3 {
+				return history, lineErr("move needs a pile, a number of cards and a pile")
+			}
+			move := Move{From: int(numbers[0]) - 1, Count: int(numbers[1]), To: int(numbers[2]) - 1}
+			history.Actions = append(history.Actions, Action{Kind: ActionMove, Move: move})
+		case string(ActionDeal), string(ActionUndo), string(ActionRedo), string(ActionCollect):
+			history.Actions = append(history.Actions, Action{Kind: ActionKind(fields[0])})
+		default:
+			return history, lineErr("unknown action " + strconv.Quote(fields[0]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return history, err
+	}
+	if version == 0 || history.Difficulty == 0 || history.Seed == 0 {
+		return history, errors.New("file is missing its version, difficulty or seed")
+	}
+
+	game, err := history.Replay()
+	if err != nil {
+		return history, err
+	}
+	// Take the piles cleared from the game rather than the file.
+	history.Actions = game.history
+	return history, nil
+}
+
+// parseNumbers parses fields as numbers, skipping the word "cleared"
+// and anything after it.
+func parseNumbers(fields []string) ([]int64, error) {
+	var numbers []int64
+	for _, field := range fields {
+		if field == "cleared" {
+			break
+		}
+		number, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+// Start returns the game of history before any actions.
+func (history History) Start() Game {
+	return NewGame(history.Difficulty, history.Seed, history.Rules)
+}
+
+// Replay returns the game of history after all of its actions.
+// Returns an error if any of the actions can't be played.
+func (history History) Replay() (Game, error) {
+	game := history.Start()
+	for i, action := range history.Actions {
+		if err := game.ApplyAction(action); err != nil {
+			return game, fmt.Errorf("action %d (%s) can't be played: %v", i+1, action.Kind, err)
+		}
+	}
+	return game, nil
+}
+
+// GameAt returns the game of history after its first step actions.
+func (history History) GameAt(step int) Game {
+	game := history.Start()
+	for _, action := range history.Actions[:step] {
+		game.ApplyAction(action)
+	}
+	return game
+}
@@ -0,0 +1,85 @@
+package engine
+
+import "fmt"
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// Move is a move of the top count cards of the pile from
+// onto the pile to, or dealing a row of cards from the deck
+// if deal is true.
+type Move struct {
+	From  int
+	Count int
+	To    int
+	Deal  bool
+}
+
+// DealMove is the Move that deals more cards from the deck.
+var DealMove = Move{Deal: true}
+
+// LegalMove is a move that the rules allow, along with what it
+// would do to the piles.
+type LegalMove struct {
+	Move
+	InSuit        bool // the run lands on a card of its own suit
+	Reveals       bool // a face down card is turned over
+	EmptiesColumn bool // the pile the run is moved from is left empty
+}
+
+// ToString describes move to the player, numbering the piles from 1.
+func (move Move) ToString() string {
+	if move.Deal {
+		return "deal from the stock"
+	}
+	return fmt.Sprintf("move %d from pile %d to pile %d",
+		move.Count, move.From+1, move.To+1)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Legal moves
+///////////////////////////////////////////////////////////////////////////////
+
+// LegalMoves returns every move the rules allow in game: each run of
+// cards that can be moved together onto each pile it can go on, from
+// the shortest run of the leftmost pile, followed by a deal from the
+// stock if dealing is allowed.
+func (game Game) LegalMoves() []LegalMove {
+	var moves []LegalMove
+	for from := 0; from < NUM_PILES; from++ {
+		pile := game.piles[from]
+		for n := 1; pile.TopNMovable(n); n++ {
+			for to := 0; to < NUM_PILES; to++ {
+				if game.CanMoveRun(from, n, to) {
+					moves = append(moves, game.describeMove(Move{From: from, Count: n, To: to}))
+				}
+			}
+		}
+	}
+	if game.CanDeal() == nil {
+		moves = append(moves, LegalMove{Move: DealMove})
+	}
+	return moves
+}
+
+// describeMove returns move along with what it would do to the piles.
+// move must be a move of cards between piles.
+func (game Game) describeMove(move Move) LegalMove {
+	from := game.piles[move.From]
+	onto := game.piles[move.To].PeekNthCard(0)
+	wholePile := move.Count == from.visible.Size()
+	return LegalMove{
+		Move:          move,
+		InSuit:        !onto.isBlank() && onto.suit == from.PeekNthCard(move.Count-1).suit,
+		Reveals:       wholePile && !from.invisible.IsEmpty(),
+		EmptiesColumn: wholePile && from.invisible.IsEmpty(),
+	}
+}
+
+// IsStuck returns true if the game hasn't been won but there is
+// nothing left the player can do: no cards can be moved, no more
+// cards can be dealt, and there are no full stacks to collect.
+func (game Game) IsStuck() bool {
+	return !game.CheckWon() && !game.HasFullStack() && len(game.LegalMoves()) == 0
+}
@@ -1,9 +1,6 @@
-package main
+package engine
 
-import (
-	"github.com/gdamore/tcell"
-	// "fmt"
-)
+// import "fmt"
 
 ///////////////////////////////////////////////////////////////////////////////
 // Data Types
@@ -21,18 +18,21 @@ type Pile struct {
 
 // TopNMovable returns true if the top n cards in the visible
 // part of the Pile can be moved together. Cards can be moved
-// together if the cards are in ascending order starting from
-// the top.
+// together if the cards are all the same suit and in ascending
+// order starting from the top.
 func (pile Pile) TopNMovable(n int) bool {
 	// fmt.Println(pile.visible.ToString())
 	if n > pile.visible.Size() {
 		return false
 	}
-	var cards []Card = pile.visible.PeekTopNCards(n)
+	cards, err := pile.visible.PeekTopNCards(n)
+	if err != nil {
+		return false
+	}
 	for i, v := range cards {
 		if i+1 < len(cards) {
 			//fmt.Println(v.value, ", ", cards[i+1].value)
-			if v.value-1 != cards[i+1].value {
+			if v.value-1 != cards[i+1].value || v.suit != cards[i+1].suit {
 				return false
 			}
 		}
@@ -40,6 +40,17 @@ func (pile Pile) TopNMovable(n int) bool {
 	return true
 }
 
+// MovableRun returns how many cards from the top of the visible
+// part of the Pile can be moved together. It is at least 1, even
+// for an empty pile.
+func (pile Pile) MovableRun() int {
+	n := 1
+	for pile.TopNMovable(n + 1) {
+		n++
+	}
+	return n
+}
+
 // PeekNthCard returns the nth card from the top of the
 // visible part of the pile. It returns a Card with NoneValue
 // and NoneSuit if there is not an nth card.
@@ -51,37 +62,36 @@ func (pile Pile) PeekNthCard(n int) Card {
 // visible portion of a Pile.
 // Top is defined by the next ones to be drawn.
 // The Cards are removed.
-func (pile *Pile) GetTopNCards(n int) []Card {
-	moved := pile.visible.GetTopNCards(n)
+func (pile *Pile) GetTopNCards(n int) ([]Card, error) {
+	moved, err := pile.visible.GetTopNCards(n)
+	if err != nil {
+		return nil, err
+	}
 	if pile.visible.IsEmpty() {
 		pile.visible.Add(pile.invisible.Draw())
 	}
-	return moved
+	return moved, nil
 }
 
-// IsEmpty returns true iff there are no cards in the pile
-// (visible or invisible).
-func (pile Pile) IsEmpty() bool {
-	return pile.visible.IsEmpty() && pile.invisible.IsEmpty()
+// Visible returns a copy of the face up cards of pile, with the
+// top of the pile at the top of the Deck.
+func (pile Pile) Visible() Deck {
+	return pile.visible.Copy()
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// Graphics
-///////////////////////////////////////////////////////////////////////////////
+// Hidden returns a copy of the face down cards of pile.
+func (pile Pile) Hidden() Deck {
+	return pile.invisible.Copy()
+}
 
-// Render renders a pile on the terminal
-func (pile Pile) Render(s tcell.Screen, x int, y int) {
-	pile.invisible.RenderFlipped(s, x, y)
-	pile.visible.Render(s, x, y+pile.invisible.Size())
+// Copy returns a new Pile with the same cards as pile, which
+// shares no memory with pile.
+func (pile Pile) Copy() Pile {
+	return Pile{pile.visible.Copy(), pile.invisible.Copy()}
 }
 
-// Height returns the height of the pile on the screen.
-// The unit is the y coordinate used by the Box struct.
-func (pile Pile) Height() int {
-	if pile.IsEmpty() {
-		// empty pile highlights should line up with the bottom
-		// card of the pile.
-		return 2
-	}
-	return pile.invisible.Size() + pile.visible.Size()*2
+// IsEmpty returns true iff there are no cards in the pile
+// (visible or invisible).
+func (pile Pile) IsEmpty() bool {
+	return pile.visible.IsEmpty() && pile.invisible.IsEmpty()
 }
@@ -0,0 +1,43 @@
+package engine
+
+import "testing"
+
+// descending returns the cards of suit from high down to low, in the
+// order they are stacked on a pile.
+func descending(suit CardSuit, high CardValue, low CardValue) []Card {
+	var cards []Card
+	for value := high; value >= low; value-- {
+		cards = append(cards, Card{suit, value})
+	}
+	return cards
+}
+
+// faceUp returns a pile of cards, all face up, with the last card
+// on top.
+func faceUp(cards ...Card) Pile {
+	return Pile{visible: Deck{append([]Card(nil), cards...)}}
+}
+
+func TestTopNMovable(t *testing.T) {
+	mixed := []Card{{Spades, Five}, {Hearts, Four}, {Spades, Three}}
+	tests := []struct {
+		name  string
+		cards []Card
+		n     int
+		want  bool
+	}{
+		{"same-suit run", descending(Spades, Five, Three), 3, true},
+		{"part of a same-suit run", descending(Spades, Five, Three), 2, true},
+		{"mixed-suit run in order", mixed, 3, false},
+		{"top of a mixed-suit run in order", mixed, 2, false},
+		{"top card of a mixed-suit run", mixed, 1, true},
+		{"same suit out of order", []Card{{Spades, Five}, {Spades, Three}}, 2, false},
+		{"single card", []Card{{Hearts, King}}, 1, true},
+		{"more cards than the pile has", []Card{{Hearts, King}}, 2, false},
+	}
+	for _, test := range tests {
+		if got := faceUp(test.cards...).TopNMovable(test.n); got != test.want {
+			t.Errorf("%s: TopNMovable(%d) = %v, want %v", test.name, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package engine
+
+import (
+	"container/heap"
+	"strings"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// SolveMode is how much of the game the solver is allowed to see.
+type SolveMode int
+
+const (
+	// Omniscient solvers can see the face down cards and the
+	// order of the deck, and search every possible move.
+	Omniscient SolveMode = iota
+	// Fair solvers only see what a player sees. They plan moves
+	// until the next face down card is turned over, make them,
+	// and never take them back.
+	Fair
+)
+
+// SolveResult is the outcome of trying to solve a game.
+type SolveResult int
+
+const (
+	Solved SolveResult = iota
+	Unsolvable
+	GaveUp
+)
+
+// SolveOptions limit how the solver searches, so that it always
+// finishes.
+type SolveOptions struct {
+	Mode     SolveMode
+	MaxNodes int           // the most positions to look at
+	MaxTime  time.Duration // the longest time to search for
+}
+
+// Solution is what the solver found. Moves is the winning list of
+// moves when Result is Solved, and the moves tried so far otherwise.
+// Full stacks are collected as soon as they are made, so the moves
+// must be made with ApplySolved.
+type Solution struct {
+	Result  SolveResult
+	Moves   []Move
+	Nodes   int // how many positions were looked at
+	Elapsed time.Duration
+}
+
+// solver holds the state of one search for a Solution.
+type solver struct {
+	options  SolveOptions
+	deadline time.Time
+	nodes    int
+	path     []Move
+}
+
+// searchNode is one position found while searching. Positions are
+// kept as their boardKey until they are looked at, and only the
+// move that reached them afterwards, to save memory.
+type searchNode struct {
+	parent int
+	move   Move
+	key    string
+}
+
+// frontier is a max-heap of positions which have been found but not
+// looked at yet, ordered by how close they are to a win.
+type frontier []frontierItem
+
+type frontierItem struct {
+	node  int
+	value int
+}
+
+// hiddenCard stands in for face down cards when a Fair solver plans
+// its moves. Nothing can be moved onto it, and it never forms a run.
+var hiddenCard = Card{CardSuit(-1), CardValue(-1)}
+
+// PLAN_NODES is the most positions a Fair solver looks at when
+// planning the moves up to the next face down card.
+const PLAN_NODES = 2000
+
+// DefaultSolveOptions are the limits used when none are given.
+var DefaultSolveOptions = SolveOptions{Omniscient, 100000, 10 * time.Second}
+
+///////////////////////////////////////////////////////////////////////////////
+// Solving
+///////////////////////////////////////////////////////////////////////////////
+
+// Solve searches for a list of moves and deals that wins game.
+// game itself is not changed.
+func Solve(game Game, options SolveOptions) Solution {
+	start := time.Now()
+	sv := solver{
+		options:  options,
+		deadline: start.Add(options.MaxTime),
+	}
+	board := game.copyBoard()
+
+	var result SolveResult
+	if options.Mode == Fair {
+		result = sv.solveFair(board)
+	} else {
+		result = sv.solveOmniscient(board)
+	}
+	return Solution{result, sv.path, sv.nodes, time.Since(start)}
+}
+
+// solveOmniscient searches every position reachable from game,
+// looking at the most promising ones first, and leaves the moves
+// to a win in sv.path.
+func (sv *solver) solveOmniscient(game Game) SolveResult {
+	var won int = -1
+	nodes, exhausted := sv.bestFirst(game, sv.options.MaxNodes, func(board Game, node int) bool {
+		if board.CheckWon() {
+			won = node
+			return true
+		}
+		return false
+	}, true)
+	switch {
+	case won >= 0:
+		sv.path = pathTo(nodes, won)
+		return Solved
+	case exhausted:
+		return Unsolvable
+	default:
+		return GaveUp
+	}
+}
+
+// solveFair plays game the way a player who can't see the face down
+// cards would, leaving the moves made in sv.path.
+func (sv *solver) solveFair(game Game) SolveResult {
+	// Positions already played through, so that filling an empty
+	// pile and then emptying it again can't go on forever.
+	seen := map[string]bool{game.boardKey(): true}
+	for !game.CheckWon() {
+		if sv.outOfBudget() {
+			return GaveUp
+		}
+		plan := sv.plan(game)
+		if plan != nil && seen[game.afterMoves(plan).boardKey()] {
+			plan = nil
+		}
+		if plan == nil {
+			switch game.CanDeal() {
+			case nil:
+				plan = []Move{DealMove}
+			case ErrPileEmpty:
+				// Cards must be put in the empty piles before dealing.
+				move, ok := game.fillEmptyPile(seen)
+				if !ok {
+					return GaveUp
+				}
+				plan = []Move{move}
+			default:
+				return GaveUp
+			}
+		}
+		for _, move := range plan {
+			game.applyMove(move)
+			sv.path = append(sv.path, move)
+		}
+		seen[game.boardKey()] = true
+	}
+	return Solved
+}
+
+// fillEmptyPile returns the best move onto an empty pile that doesn't
+// lead to a position in seen, and false if there isn't one.
+func (game Game) fillEmptyPile(seen map[string]bool) (Move, bool) {
+	for _, move := range game.Hints() {
+		if game.piles[move.To].IsEmpty() && !seen[game.afterMoves([]Move{move}).boardKey()] {
+			return move, true
+		}
+	}
+	return Move{}, false
+}
+
+// afterMoves returns a copy of the cards of game after moves.
+func (game Game) afterMoves(moves []Move) Game {
+	board := game.copyBoard()
+	for _, move := range moves {
+		board.applyMove(move)
+	}
+	return board
+}
+
+// plan looks for the moves that improve game the most without seeing
+// any face down cards, stopping wherever a move would turn a card
+// over. Returns nil if no moves make game better.
+func (sv *solver) plan(game Game) []Move {
+	start := game.masked()
+	bestValue := evaluate(start)
+	best := -1
+	nodes, _ := sv.bestFirst(start, PLAN_NODES, func(board Game, node int) bool {
+		if value := evaluate(board); value > bestValue {
+			bestValue = value
+			best = node
+		}
+		return false
+	}, false)
+	if best < 0 {
+		return nil
+	}
+	return pathTo(nodes, best)
+}
+
+// bestFirst looks at up to limit positions reachable from start,
+// always choosing the position closest to a win next. visit is called
+// with each position looked at, and the search stops if it returns
+// true. Moves that turn over a face down card are only followed if
+// pastReveals is true, and deals are only made if it is true.
+// Returns every position found, and whether every position that can
+// be reached from start was looked at, which isn't the case if any
+// moves were skipped by isProgress.
+func (sv *solver) bestFirst(start Game, limit int, visit func(Game, int) bool,
+	pastReveals bool) ([]searchNode, bool) {
+	nodes := []searchNode{{-1, Move{}, start.boardKey()}}
+	seen := map[string]bool{nodes[0].key: true}
+	open := &frontier{{0, evaluate(start)}}
+	skipped := false
+
+	for looked := 0; open.Len() > 0; looked++ {
+		if looked >= limit || sv.outOfBudget() {
+			return nodes, false
+		}
+		current := heap.Pop(open).(frontierItem).node
+		board := boardFromKey(start, nodes[current].key)
+		nodes[current].key = ""
+		sv.nodes++
+		if visit(board, current) {
+			return nodes, false
+		}
+
+		var moves []LegalMove
+		for _, move := range board.LegalMoves() {
+			switch {
+			case move.Deal:
+				if pastReveals {
+					moves = append(moves, move)
+				}
+			case !board.isUseful(move):
+			case !board.isProgress(move.Move):
+				skipped = true
+			default:
+				moves = append(moves, move)
+			}
+		}
+		for _, move := range moves {
+			child := board.copyBoard()
+			child.applyMove(move.Move)
+			key := child.boardKey()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			nodes = append(nodes, searchNode{current, move.Move, key})
+			if move.Reveals && !pastReveals {
+				// The card turned over is unknown, so look at this
+				// position but don't go any further.
+				visit(child, len(nodes)-1)
+				continue
+			}
+			heap.Push(open, frontierItem{len(nodes) - 1, evaluate(child)})
+		}
+	}
+	return nodes, !skipped
+}
+
+// isProgress returns false for moves the solver hardly ever needs to
+// make, because they split a run that is already in suit. Moving a
+// run off a card of another suit is always allowed, since that is
+// how a card of the right suit gets onto it.
+func (game Game) isProgress(move Move) bool {
+	from := game.piles[move.From]
+	topMoved := from.PeekNthCard(move.Count - 1)
+	below := from.PeekNthCard(move.Count)
+	return below.value != topMoved.value+1 || below.suit != topMoved.suit
+}
+
+// pathTo returns the moves that reach nodes[node] from the start of
+// the search.
+func pathTo(nodes []searchNode, node int) []Move {
+	var path []Move
+	for ; nodes[node].parent >= 0; node = nodes[node].parent {
+		path = append(path, nodes[node].move)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// outOfBudget returns true once the solver has looked at as many
+// positions, or searched for as long, as its options allow.
+func (sv *solver) outOfBudget() bool {
+	return sv.nodes >= sv.options.MaxNodes || time.Now().After(sv.deadline)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Frontier heap functions, for container/heap
+///////////////////////////////////////////////////////////////////////////////
+
+func (f frontier) Len() int { return len(f) }
+
+func (f frontier) Less(i, j int) bool {
+	if f[i].value != f[j].value {
+		return f[i].value > f[j].value
+	}
+	// Among equally good positions, the newest is looked at first,
+	// so the search goes deeper rather than wider.
+	return f[i].node > f[j].node
+}
+
+func (f frontier) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+
+func (f *frontier) Push(x interface{}) { *f = append(*f, x.(frontierItem)) }
+
+func (f *frontier) Pop() interface{} {
+	old := *f
+	item := old[len(old)-1]
+	*f = old[:len(old)-1]
+	return item
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Positions
+///////////////////////////////////////////////////////////////////////////////
+
+// copyBoard returns a Game with a copy of the cards of game, and
+// nothing else.
+func (game Game) copyBoard() Game {
+	var board Game
+	board.rules = game.rules
+	board.foundation = append([]CardSuit(nil), game.foundation...)
+	board.deck = game.deck.Copy()
+	for i := 0; i < NUM_PILES; i++ {
+		board.piles[i] = game.piles[i].Copy()
+	}
+	return board
+}
+
+// masked returns a copy of the cards of game where every face down
+// card and every card in the deck is replaced by hiddenCard.
+func (game Game) masked() Game {
+	board := game.copyBoard()
+	for i := range board.deck.cards {
+		board.deck.cards[i] = hiddenCard
+	}
+	for p := 0; p < NUM_PILES; p++ {
+		for i := range board.piles[p].invisible.cards {
+			board.piles[p].invisible.cards[i] = hiddenCard
+		}
+	}
+	return board
+}
+
+// applyMove makes move in game without any checks or undo history,
+// and removes any full stacks it makes.
+func (game *Game) applyMove(move Move) {
+	if move.Deal {
+		game.dealRow()
+	} else {
+		game.moveRun(move.From, move.Count, move.To)
+	}
+	game.checkStacks()
+}
+
+// boardKey returns a string which is the same for two positions of
+// the same deal exactly when they have the same cards showing. Face
+// down cards and cards in the deck never move until they are turned
+// over, so only how many there are matters.
+func (game Game) boardKey() string {
+	var key strings.Builder
+	key.WriteByte(byte(game.deck.Size()))
+	for _, pile := range game.piles {
+		key.WriteByte(byte(pile.invisible.Size()))
+		for _, card := range pile.visible.cards {
+			key.WriteByte(cardKey(card))
+		}
+		key.WriteByte(0)
+	}
+	return key.String()
+}
+
+// boardFromKey returns the position of the same deal as start which
+// has the boardKey key. start must be a position from earlier in the
+// game, with at least as many face down cards and cards in the deck.
+func boardFromKey(start Game, key string) Game {
+	var board Game
+	board.rules = start.rules
+	board.deck.cards = append([]Card(nil), start.deck.cards[:key[0]]...)
+	pos := 1
+	for p := 0; p < NUM_PILES; p++ {
+		hidden := start.piles[p].invisible.cards[:key[pos]]
+		board.piles[p].invisible.cards = append([]Card(nil), hidden...)
+		for pos++; key[pos] != 0; pos++ {
+			board.piles[p].visible.cards = append(board.piles[p].visible.cards,
+				keyCard(key[pos]))
+		}
+		pos++
+	}
+	return board
+}
+
+// cardKey packs card into a single non-zero byte.
+func cardKey(card Card) byte {
+	if card == hiddenCard {
+		return 0xff
+	}
+	return byte(card.suit)<<4 | byte(card.value)
+}
+
+// keyCard unpacks a card packed by cardKey.
+func keyCard(b byte) Card {
+	if b == 0xff {
+		return hiddenCard
+	}
+	return Card{CardSuit(b >> 4), CardValue(b & 0xf)}
+}
+
+// evaluate returns how close game is to being won. It only looks at
+// cards that are face up.
+func evaluate(game Game) int {
+	value := 0
+	cards := game.deck.Size()
+	for _, pile := range game.piles {
+		cards += pile.visible.Size() + pile.invisible.Size()
+		value -= 20 * pile.invisible.Size()
+		if pile.IsEmpty() {
+			value += 15
+		}
+		for i := 0; i+1 < pile.visible.Size(); i++ {
+			lower := pile.visible.cards[i+1]
+			upper := pile.visible.cards[i]
+			if lower.value == upper.value-1 {
+				if lower.suit == upper.suit {
+					value += 10
+				} else {
+					value += 3
+				}
+			}
+		}
+	}
+	// Full stacks are removed from the game, so any missing cards
+	// were part of one.
+	value += 1000 * ((NUM_CARDS - cards) / NUM_VALUES)
+	return value
+}
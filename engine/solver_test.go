@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSolveMovesOffMixedRun checks that the solver will move a card
+// off one of another suit that it follows in order. 7♥ and the
+// spades from 7 down are each on an 8 of the other suit, so the only
+// way to win is to put one of them in an empty pile first.
+func TestSolveMovesOffMixedRun(t *testing.T) {
+	var game Game
+	game.piles[0] = faceUp(append(descending(Spades, King, Eight), Card{Hearts, Seven})...)
+	game.piles[1] = faceUp(append(descending(Hearts, King, Eight), descending(Spades, Seven, Ace)...)...)
+	game.piles[2] = faceUp(descending(Hearts, Six, Ace)...)
+
+	options := SolveOptions{Omniscient, 10000, 10 * time.Second}
+	solution := Solve(game, options)
+	if solution.Result != Solved {
+		t.Fatalf("Solve result = %v, want Solved", solution.Result)
+	}
+	for _, move := range solution.Moves {
+		if err := game.Apply(move); err != nil {
+			t.Fatalf("move %s: %v", move.ToString(), err)
+		}
+	}
+	if !game.CheckWon() {
+		t.Errorf("the solution doesn't win the game")
+	}
+}
+
+// TestSolveManualCollect checks that a solution for a game where full
+// stacks are collected by hand wins when played with ApplySolved.
+func TestSolveManualCollect(t *testing.T) {
+	game := NewGame(OneSuit, 1, Rules{ManualCollect: true})
+	solution := Solve(game, DefaultSolveOptions)
+	if solution.Result != Solved {
+		t.Fatalf("Solve result = %v, want Solved", solution.Result)
+	}
+	for _, move := range solution.Moves {
+		if err := game.ApplySolved(move); err != nil {
+			t.Fatalf("move %s: %v", move.ToString(), err)
+		}
+	}
+	if !game.CheckWon() {
+		t.Errorf("the solution doesn't win the game")
+	}
+}
@@ -0,0 +1,78 @@
+package engine
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// snapshot is a copy of all of the cards in a Game at one point
+// in time. Restoring a snapshot undoes everything that happened
+// to the cards since it was taken, including cards flipped over
+// and full stacks removed.
+type snapshot struct {
+	deck       Deck
+	piles      [NUM_PILES]Pile
+	foundation []CardSuit
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Undo and redo functions
+///////////////////////////////////////////////////////////////////////////////
+
+// takeSnapshot returns a copy of the current cards of the game.
+func (game Game) takeSnapshot() snapshot {
+	var state snapshot
+	state.deck = game.deck.Copy()
+	state.foundation = append([]CardSuit(nil), game.foundation...)
+	for i := 0; i < NUM_PILES; i++ {
+		state.piles[i] = game.piles[i].Copy()
+	}
+	return state
+}
+
+// restore puts the cards of the game back to how they were when
+// state was taken.
+func (game *Game) restore(state snapshot) {
+	game.deck = state.deck.Copy()
+	game.foundation = append([]CardSuit(nil), state.foundation...)
+	for i := 0; i < NUM_PILES; i++ {
+		game.piles[i] = state.piles[i].Copy()
+	}
+}
+
+// saveUndo records state, taken just before an action that has now
+// been made, so that the action can be undone. Any actions that were
+// undone can no longer be redone.
+func (game *Game) saveUndo(state snapshot) {
+	game.undo = append(game.undo, state)
+	game.redo = nil
+}
+
+// Undo takes back the last action, which costs a point. Returns
+// false if there was nothing to undo.
+func (game *Game) Undo() bool {
+	if len(game.undo) == 0 {
+		return false
+	}
+	last := game.undo[len(game.undo)-1]
+	game.undo = game.undo[:len(game.undo)-1]
+	game.redo = append(game.redo, game.takeSnapshot())
+	game.restore(last)
+	game.undos++
+	game.record(Action{Kind: ActionUndo})
+	return true
+}
+
+// Redo does the last action that was undone again, which counts
+// as a move. Returns false if there was nothing to redo.
+func (game *Game) Redo() bool {
+	if len(game.redo) == 0 {
+		return false
+	}
+	next := game.redo[len(game.redo)-1]
+	game.redo = game.redo[:len(game.redo)-1]
+	game.undo = append(game.undo, game.takeSnapshot())
+	game.restore(next)
+	game.moves++
+	game.record(Action{Kind: ActionRedo})
+	return true
+}
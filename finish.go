@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+// AUTO_FINISH_DELAY is how long each move made by auto finish is
+// shown for.
+const AUTO_FINISH_DELAY = 150 * time.Millisecond
+
+// AutoFinishOptions limits the search for the moves that finish a
+// game. With every card face up, it should not need to look far.
+var AutoFinishOptions = engine.SolveOptions{Mode: engine.Omniscient, MaxNodes: 20000, MaxTime: 2 * time.Second}
+
+///////////////////////////////////////////////////////////////////////////////
+// Auto finish
+///////////////////////////////////////////////////////////////////////////////
+
+// AutoFinish plays out the rest of game, showing each move on s.
+// Returns true if the game has been won, and an error if one of
+// the moves could not be played.
+func AutoFinish(s tcell.Screen, layout Layout, game *Game) (bool, error) {
+	if !game.CanAutoFinish() {
+		game.message = "The game can only be finished automatically once every card is face up"
+		return false, nil
+	}
+	solution := engine.Solve(game.Game, AutoFinishOptions)
+	if solution.Result != engine.Solved {
+		game.message = "Could not find a way to finish the game"
+		return false, nil
+	}
+	game.toMove = false
+	for _, move := range solution.Moves {
+		game.highlighted = Selected{move.To, 1, 1}
+		if err := game.ApplySolved(move); err != nil {
+			return false, err
+		}
+		s.Clear()
+		game.Render(s, layout)
+		s.Show()
+		time.Sleep(AUTO_FINISH_DELAY)
+	}
+	return game.CheckWon(), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Graphics
+///////////////////////////////////////////////////////////////////////////////
+
+// RenderFoundation renders a space for each full stack. The stacks
+// that have been completed show their suit, and how many stacks of
+// that suit have been completed out of how many there are.
+func (game Game) RenderFoundation(s tcell.Screen, layout Layout) {
+	perSuit := engine.NUM_STACKS / len(game.Difficulty().Suits())
+	foundation := game.Foundation()
+	counts := make(map[engine.CardSuit]int)
+	for i := 0; i < engine.NUM_STACKS; i++ {
+		x1, y1, x2, y2 := layout.FoundationBox(i)
+		if i >= len(foundation) {
+			box := Box{s, x1, y1, x2, y2, tcell.StyleDefault, "", true}
+			box.Draw()
+			continue
+		}
+		suit := foundation[i]
+		counts[suit]++
+		box := Box{s, x1, y1, x2, y2, CurrentTheme.face, "", false}
+		box.Draw()
+		style := SuitStyle(suit)
+		name := string(SuitRune(s, suit)) + " " + suit.ToString()
+		count := fmt.Sprintf("%d of %d", counts[suit], perSuit)
+		if layout.compact {
+			// There is only room for the text in the edges of the box.
+			name = string(SuitRune(s, suit))
+			count = fmt.Sprintf("%d/%d", counts[suit], perSuit)
+			y1, y2 = y1-1, y2+1
+		} else if utf8.RuneCountInString(name) >= layout.width {
+			name = string(SuitRune(s, suit))
+		}
+		emitStr(s, x1+1, y1+1, x2-1, y1+1, style, name)
+		emitStr(s, x1+1, y2-1, x2-1, y2-1, style, count)
+	}
+}
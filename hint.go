@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+///////////////////////////////////////////////////////////////////////////////
+// Hint functions
+///////////////////////////////////////////////////////////////////////////////
+
+// NextHint shows the player a suggested move. The first call shows
+// the best move, and calling it again while the hint is shown cycles
+// through the other possible moves.
+func (game *Game) NextHint() {
+	if game.showHint {
+		game.hintIndex = (game.hintIndex + 1) % len(game.hints)
+	} else {
+		game.hints = game.Hints()
+		game.hintIndex = 0
+	}
+	if len(game.hints) == 0 {
+		game.showHint = false
+		if err := game.CanDeal(); err != nil {
+			game.message = "No moves available, and " + err.Error()
+		} else {
+			game.message = "No moves available, try dealing from the stock"
+		}
+		return
+	}
+	game.showHint = true
+	hint := game.hints[game.hintIndex]
+	game.message = fmt.Sprintf("Hint %d of %d: %s",
+		game.hintIndex+1, len(game.hints), hint.ToString())
+}
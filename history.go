@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Exporting history
+///////////////////////////////////////////////////////////////////////////////
+
+// ExportHistory writes the history of game to a new text file in the
+// current directory, and returns the file's name.
+func ExportHistory(game Game) (string, error) {
+	name := fmt.Sprintf("spider-%d-%s.txt", game.Seed(), time.Now().Format("20060102-150405"))
+	file, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if err := game.History().Write(file); err != nil {
+		return "", err
+	}
+	return name, file.Close()
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// Action is something the player can do with a key during a game.
+type Action int
+
+const (
+	ActionNone       Action = iota // the key doesn't do anything
+	ActionLeft                     // move the cursor one pile left
+	ActionRight                    // move the cursor one pile right
+	ActionUp                       // highlight one more card, or go up to the stock
+	ActionDown                     // highlight one less card, or go down to the piles
+	ActionSelect                   // pick up the highlighted cards, or put them down
+	ActionCancel                   // let go of the selected cards
+	ActionRun                      // highlight the longest run of the pile
+	ActionPile                     // pick up the cards of a pile, or put them down on it
+	ActionDeal                     // deal from the stock
+	ActionUndo                     // take back the last action
+	ActionRedo                     // do the last action taken back again
+	ActionHint                     // show the next hint
+	ActionCollect                  // collect full stacks into the foundation
+	ActionFinish                   // play out the rest of the game
+	ActionPause                    // stop the clock
+	ActionSave                     // save the game
+	ActionLoad                     // load the saved game
+	ActionExport                   // export the move history
+	ActionPlayDeal                 // give up on the game and play a deal chosen by number
+	ActionTheme                    // change to the next theme
+	ActionScrollUp                 // scroll the piles up
+	ActionScrollDown               // scroll the piles down
+	ActionRedraw                   // redraw the whole screen
+)
+
+// actionNames are the names of each Action in the config file.
+// Pile actions are named "pile1" to "pile10" instead.
+var actionNames = [...]string{
+	ActionLeft:       "left",
+	ActionRight:      "right",
+	ActionUp:         "up",
+	ActionDown:       "down",
+	ActionSelect:     "select",
+	ActionCancel:     "cancel",
+	ActionRun:        "run",
+	ActionDeal:       "deal",
+	ActionUndo:       "undo",
+	ActionRedo:       "redo",
+	ActionHint:       "hint",
+	ActionCollect:    "collect",
+	ActionFinish:     "finish",
+	ActionPause:      "pause",
+	ActionSave:       "save",
+	ActionLoad:       "load",
+	ActionExport:     "export",
+	ActionPlayDeal:   "play-deal",
+	ActionTheme:      "theme",
+	ActionScrollUp:   "scroll-up",
+	ActionScrollDown: "scroll-down",
+	ActionRedraw:     "redraw",
+}
+
+// legendActions are the actions shown in the legend, most useful
+// first, along with what the legend calls them. Pairs of opposite
+// actions share a label.
+var legendActions = []struct {
+	actions []Action
+	label   string
+}{
+	{[]Action{ActionLeft, ActionRight}, "move"},
+	{[]Action{ActionUp, ActionDown}, "more/fewer"},
+	{[]Action{ActionSelect}, "select"},
+	{[]Action{ActionPile}, "piles"},
+	{[]Action{ActionDeal}, "deal"},
+	{[]Action{ActionUndo}, "undo"},
+	{[]Action{ActionHint}, "hint"},
+	{[]Action{ActionPause}, "pause"},
+	{[]Action{ActionCancel}, "drop"},
+	{[]Action{ActionRedo}, "redo"},
+	{[]Action{ActionRun}, "run"},
+	{[]Action{ActionCollect}, "collect"},
+	{[]Action{ActionFinish}, "finish"},
+	{[]Action{ActionSave}, "save"},
+	{[]Action{ActionLoad}, "load"},
+	{[]Action{ActionExport}, "export"},
+	{[]Action{ActionPlayDeal}, "deal #"},
+	{[]Action{ActionTheme}, "colours"},
+}
+
+// Key is a key on the keyboard: a character when key is
+// tcell.KeyRune, and a special key such as tcell.KeyEnter otherwise.
+type Key struct {
+	key tcell.Key
+	ch  rune
+}
+
+// Binding is what pressing key does during a game.
+type Binding struct {
+	key    Key
+	action Action
+	pile   int // which pile, for ActionPile
+}
+
+// Keymap is the set of keys the game is played with.
+type Keymap struct {
+	name     string
+	bindings []Binding // the keys for each action, in the order they are listed
+}
+
+// ArrowKeys moves the cursor with the arrow keys.
+var ArrowKeys = Keymap{"arrows", nil}.
+	bind(ActionLeft, 0, special(tcell.KeyLeft)).
+	bind(ActionRight, 0, special(tcell.KeyRight)).
+	bind(ActionUp, 0, special(tcell.KeyUp)).
+	bind(ActionDown, 0, special(tcell.KeyDown)).
+	bind(ActionSelect, 0, char(' '), special(tcell.KeyEnter)).
+	bind(ActionCancel, 0, special(tcell.KeyBackspace)).
+	bind(ActionRun, 0, char('a')).
+	bind(ActionUndo, 0, char('u'), special(tcell.KeyCtrlZ)).
+	bind(ActionRedo, 0, char('r'), special(tcell.KeyCtrlY)).
+	bind(ActionHint, 0, char('h')).
+	bind(ActionCollect, 0, char('c')).
+	bind(ActionFinish, 0, char('f')).
+	bind(ActionPause, 0, char('p')).
+	bind(ActionSave, 0, char('s')).
+	bind(ActionLoad, 0, char('l')).
+	bind(ActionExport, 0, char('x')).
+	bind(ActionPlayDeal, 0, char('n')).
+	bind(ActionTheme, 0, char('o')).
+	bind(ActionScrollUp, 0, special(tcell.KeyPgUp)).
+	bind(ActionScrollDown, 0, special(tcell.KeyPgDn)).
+	bind(ActionRedraw, 0, special(tcell.KeyCtrlL))
+
+// VimKeys moves the cursor with h, j, k and l as well as the arrow
+// keys, so hints move to ? and loading to L.
+var VimKeys = ArrowKeys.named("vim").
+	bind(ActionHint, 0, char('?')).
+	bind(ActionLoad, 0, char('L')).
+	bind(ActionLeft, 0, char('h'), special(tcell.KeyLeft)).
+	bind(ActionRight, 0, char('l'), special(tcell.KeyRight)).
+	bind(ActionUp, 0, char('k'), special(tcell.KeyUp)).
+	bind(ActionDown, 0, char('j'), special(tcell.KeyDown)).
+	bind(ActionRedo, 0, char('r'), special(tcell.KeyCtrlR), special(tcell.KeyCtrlY)).
+	bind(ActionScrollUp, 0, special(tcell.KeyCtrlU), special(tcell.KeyPgUp)).
+	bind(ActionScrollDown, 0, special(tcell.KeyCtrlD), special(tcell.KeyPgDn))
+
+// NumericKeys adds a key for each pile to ArrowKeys, from 1 for the
+// first pile to 0 for the tenth, and d to deal.
+var NumericKeys = ArrowKeys.named("numeric").
+	bind(ActionPile, 0, char('1')).
+	bind(ActionPile, 1, char('2')).
+	bind(ActionPile, 2, char('3')).
+	bind(ActionPile, 3, char('4')).
+	bind(ActionPile, 4, char('5')).
+	bind(ActionPile, 5, char('6')).
+	bind(ActionPile, 6, char('7')).
+	bind(ActionPile, 7, char('8')).
+	bind(ActionPile, 8, char('9')).
+	bind(ActionPile, 9, char('0')).
+	bind(ActionDeal, 0, char('d'))
+
+// Keymaps are the keymaps the player can choose from, with the
+// default first.
+var Keymaps = []Keymap{ArrowKeys, VimKeys, NumericKeys}
+
+// CurrentKeys are the keys the game is played with. They are set
+// from the config file with MakeKeymap.
+var CurrentKeys = ArrowKeys
+
+///////////////////////////////////////////////////////////////////////////////
+// Keys
+///////////////////////////////////////////////////////////////////////////////
+
+// char returns the Key that types ch.
+func char(ch rune) Key {
+	return Key{tcell.KeyRune, ch}
+}
+
+// special returns the Key for a key that doesn't type a character.
+func special(key tcell.Key) Key {
+	return Key{key: key}
+}
+
+// KeyOf returns the key pressed in ev.
+func KeyOf(ev *tcell.EventKey) Key {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		return char(ev.Rune())
+	case tcell.KeyBackspace2:
+		// Terminals differ in which code they send for Backspace.
+		return special(tcell.KeyBackspace)
+	}
+	return special(ev.Key())
+}
+
+// ToString returns the name of key, as shown to the player and
+// written in the config file.
+func (key Key) ToString() string {
+	if key.key == tcell.KeyRune {
+		if key.ch == ' ' {
+			return "Space"
+		}
+		return string(key.ch)
+	}
+	if name, ok := tcell.KeyNames[key.key]; ok {
+		return name
+	}
+	return "Key" + strconv.Itoa(int(key.key))
+}
+
+// ParseKey returns the key called name: a single character, Space,
+// or one of the names tcell gives special keys, such as Enter, PgUp
+// or Ctrl-Z. Returns an error if there is no such key.
+func ParseKey(name string) (Key, error) {
+	if runes := []rune(name); len(runes) == 1 {
+		return char(runes[0]), nil
+	}
+	if strings.EqualFold(name, "Space") {
+		return char(' '), nil
+	}
+	for key, keyName := range tcell.KeyNames {
+		if strings.EqualFold(name, keyName) && key != tcell.KeyBackspace2 {
+			return special(key), nil
+		}
+	}
+	return Key{}, errors.New("there is no key called " + name)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Keymap functions
+///////////////////////////////////////////////////////////////////////////////
+
+// named returns a copy of keymap called name.
+func (keymap Keymap) named(name string) Keymap {
+	return Keymap{name, keymap.bindings}
+}
+
+// bind returns a copy of keymap with keys doing action, and pile for
+// ActionPile, instead of whatever they did before. Any other keys
+// for the same action no longer do it.
+func (keymap Keymap) bind(action Action, pile int, keys ...Key) Keymap {
+	var bindings []Binding
+	for _, binding := range keymap.bindings {
+		if binding.action == action && binding.pile == pile {
+			continue
+		}
+		if containsKey(keys, binding.key) {
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+	for _, key := range keys {
+		bindings = append(bindings, Binding{key, action, pile})
+	}
+	return Keymap{keymap.name, bindings}
+}
+
+// containsKey returns true if key is one of keys.
+func containsKey(keys []Key, key Key) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns what the key pressed in ev does, which is
+// ActionNone if it isn't bound to anything.
+func (keymap Keymap) Lookup(ev *tcell.EventKey) Binding {
+	key := KeyOf(ev)
+	for _, binding := range keymap.bindings {
+		if binding.key == key {
+			return binding
+		}
+	}
+	return Binding{key: key}
+}
+
+// Keys returns the keys for action, and pile for ActionPile, in the
+// order they were bound.
+func (keymap Keymap) Keys(action Action, pile int) []Key {
+	var keys []Key
+	for _, binding := range keymap.bindings {
+		if binding.action == action && binding.pile == pile {
+			keys = append(keys, binding.key)
+		}
+	}
+	return keys
+}
+
+// Describe returns the keys for action joined with "or", such as
+// "u or Ctrl-Z", for telling the player which key to press.
+func (keymap Keymap) Describe(action Action) string {
+	var names []string
+	for _, key := range keymap.Keys(action, 0) {
+		names = append(names, key.ToString())
+	}
+	if len(names) == 0 {
+		return "(no key)"
+	}
+	return strings.Join(names, " or ")
+}
+
+// HasPileKeys returns true if each pile has a key of its own.
+func (keymap Keymap) HasPileKeys() bool {
+	for pile := 0; pile < engine.NUM_PILES; pile++ {
+		if len(keymap.Keys(ActionPile, pile)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FindKeymap returns the keymap called name, or false if there is
+// no such keymap.
+func FindKeymap(name string) (Keymap, bool) {
+	for _, keymap := range Keymaps {
+		if keymap.name == name {
+			return keymap, true
+		}
+	}
+	return Keymap{}, false
+}
+
+// NextKeymapName returns the name of the keymap after the one called
+// name, going back to the first keymap after the last.
+func NextKeymapName(name string) string {
+	for i, keymap := range Keymaps {
+		if keymap.name == name {
+			return Keymaps[(i+1)%len(Keymaps)].name
+		}
+	}
+	return Keymaps[0].name
+}
+
+// MakeKeymap returns the keymap called name, or the default keymap
+// if name is "", changed by bindings. bindings gives the keys for
+// each action by its name in the config file, such as
+// {"undo": ["z"]}; an empty list of keys leaves the action without
+// a key. Returns an error if there is no such keymap, action or key.
+func MakeKeymap(name string, bindings map[string][]string) (Keymap, error) {
+	keymap := Keymaps[0]
+	if name != "" {
+		var ok bool
+		if keymap, ok = FindKeymap(name); !ok {
+			return keymap, errors.New("there are no keys called " + name)
+		}
+	}
+	// Bindings are applied in a fixed order, so that if two actions
+	// are given the same key it is always the same one that gets it.
+	var names []string
+	for actionName := range bindings {
+		names = append(names, actionName)
+	}
+	sort.Strings(names)
+	for _, actionName := range names {
+		keyNames := bindings[actionName]
+		action, pile, err := parseAction(actionName)
+		if err != nil {
+			return keymap, err
+		}
+		var keys []Key
+		for _, keyName := range keyNames {
+			key, err := ParseKey(keyName)
+			if err != nil {
+				return keymap, err
+			}
+			keys = append(keys, key)
+		}
+		keymap = keymap.bind(action, pile, keys...)
+	}
+	return keymap, nil
+}
+
+// parseAction returns the action called name in the config file,
+// along with the pile for pile actions. Returns an error if there is
+// no such action.
+func parseAction(name string) (Action, int, error) {
+	for action, actionName := range actionNames {
+		if actionName != "" && actionName == name {
+			return Action(action), 0, nil
+		}
+	}
+	if strings.HasPrefix(name, "pile") {
+		pile, err := strconv.Atoi(strings.TrimPrefix(name, "pile"))
+		if err == nil && pile >= 1 && pile <= engine.NUM_PILES {
+			return ActionPile, pile - 1, nil
+		}
+	}
+	return ActionNone, 0, errors.New("there is no action called " + name)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Graphics
+///////////////////////////////////////////////////////////////////////////////
+
+// KeyHelp returns lines telling the player which keys do what in
+// keymap, for the instruction screen.
+func KeyHelp(keymap Keymap) []string {
+	lines := []string{
+		"Use " + keymap.Describe(ActionLeft) + " and " + keymap.Describe(ActionRight) +
+			" to move and " + keymap.Describe(ActionSelect) +
+			" to select or move cards, or click and drag with the mouse",
+		keymap.Describe(ActionUp) + " and " + keymap.Describe(ActionDown) +
+			" change how many cards are highlighted, " + keymap.Describe(ActionRun) +
+			" highlights the longest run, and " + keymap.Describe(ActionCancel) + " cancels",
+	}
+	if keymap.HasPileKeys() {
+		lines = append(lines, "Press a pile's key ("+keymap.pileRange()+
+			") to pick up its cards and another pile's key to move them there, and "+
+			keymap.Describe(ActionDeal)+" to deal")
+	}
+	return append(lines,
+		"Press "+keymap.Describe(ActionUndo)+" to undo, "+keymap.Describe(ActionRedo)+
+			" to redo, "+keymap.Describe(ActionHint)+" for a hint and "+
+			keymap.Describe(ActionPause)+" to pause",
+		"Press "+keymap.Describe(ActionSave)+" to save and "+keymap.Describe(ActionLoad)+
+			" to load, "+keymap.Describe(ActionPlayDeal)+" to switch to a specific deal, and ESC to save and exit")
+}
+
+// legendName returns a short name for key, with arrows for the
+// arrow keys if s can show them.
+func legendName(s tcell.Screen, key Key) string {
+	arrows := map[tcell.Key]rune{
+		tcell.KeyLeft:  '←',
+		tcell.KeyRight: '→',
+		tcell.KeyUp:    '↑',
+		tcell.KeyDown:  '↓',
+	}
+	if arrow, ok := arrows[key.key]; ok && s.CanDisplay(arrow, false) {
+		return string(arrow)
+	}
+	if key.key == tcell.KeyBackspace {
+		return "Bksp"
+	}
+	return key.ToString()
+}
+
+// pileRange returns the keys for the first and last piles, such as
+// "1-0".
+func (keymap Keymap) pileRange() string {
+	first := keymap.Keys(ActionPile, 0)
+	last := keymap.Keys(ActionPile, engine.NUM_PILES-1)
+	return first[0].ToString() + "-" + last[0].ToString()
+}
+
+// RenderLegend renders a bar along the bottom of the screen, below
+// the piles, showing the first key for each action in keymap. The
+// least useful actions are left out if they don't all fit.
+func RenderLegend(s tcell.Screen, layout Layout, keymap Keymap) {
+	if layout.screenHeight == 0 {
+		return
+	}
+	y := layout.screenHeight
+	leave := "Esc leave"
+	legend := ""
+	for _, entry := range legendActions {
+		var names []string
+		for _, action := range entry.actions {
+			if action == ActionPile && keymap.HasPileKeys() {
+				names = append(names, keymap.pileRange())
+			} else if keys := keymap.Keys(action, 0); len(keys) > 0 {
+				names = append(names, legendName(s, keys[0]))
+			}
+		}
+		if len(names) < len(entry.actions) {
+			continue
+		}
+		item := strings.Join(names, "/") + " " + entry.label + "  "
+		if utf8.RuneCountInString(legend+item+leave) > layout.Right()-layout.x-1 {
+			break
+		}
+		legend += item
+	}
+	legend += leave
+	style := CurrentTheme.status
+	for x := layout.x; x <= layout.Right(); x++ {
+		s.SetContent(x, y, ' ', nil, style)
+	}
+	emitStr(s, layout.x+1, y, layout.Right(), y, style, legend)
+}
@@ -0,0 +1,279 @@
+package main
+
+import "solitaire/engine"
+
+// FIT_HIDDEN and FIT_VISIBLE are the number of face down and face up
+// cards in the pile that a layout must have room for to fit the
+// screen, which is about as long as piles get in the middle of a game.
+const FIT_HIDDEN = 5
+const FIT_VISIBLE = 6
+
+// LEGEND_ROWS is the number of rows kept at the bottom of the screen
+// for the legend of keys.
+const LEGEND_ROWS = 1
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// CardSize is how big cards are drawn, and how far apart they are.
+type CardSize struct {
+	width       int  // columns from the left edge of a card to its right edge
+	height      int  // rows from the top edge of a card to its bottom edge
+	gap         int  // columns between piles
+	hiddenStep  int  // rows between the tops of face down cards in a pile
+	visibleStep int  // rows between the tops of face up cards in a pile
+	compact     bool // whether cards only have a label in their top edge
+}
+
+// LargeCards, MediumCards and SmallCards are the sizes a Layout
+// chooses from, largest first. Small cards fit in an 80 by 24
+// terminal.
+var LargeCards = CardSize{11, 7, 1, 1, 2, false}
+var MediumCards = CardSize{7, 5, 1, 1, 2, false}
+var SmallCards = CardSize{5, 2, 1, 1, 1, true}
+
+// SCROLL_STEP is how many rows the piles scroll for each turn of
+// the mouse wheel.
+const SCROLL_STEP = 3
+
+// Layout describes where each part of a Game is drawn on the
+// screen. Rendering and working out what the mouse is over both
+// use it, so they always agree.
+type Layout struct {
+	CardSize
+	x            int // the left of the game
+	y            int // the top of the game
+	screenHeight int // the number of rows above the legend, or 0 if there is no limit
+	scroll       int // how many rows the piles have been scrolled up by
+}
+
+// HitKind is the kind of thing at a point on the screen.
+type HitKind int
+
+const (
+	HitNothing HitKind = iota
+	HitStock
+	HitPile
+	HitFoundation
+)
+
+// Hit describes what is at a point on the screen.
+type Hit struct {
+	kind HitKind
+	pile int // which pile was hit, for HitPile
+	// numCards is how many face up cards there are from the card hit
+	// to the top of the pile, or 0 if a face down card or the space
+	// of an empty pile was hit.
+	numCards int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Layout functions
+///////////////////////////////////////////////////////////////////////////////
+
+// NewLayout returns the Layout of a game drawn with cards of size,
+// with its upper-left corner at x, y.
+func NewLayout(x int, y int, size CardSize) Layout {
+	return Layout{size, x, y, 0, 0}
+}
+
+// FitLayout returns the Layout with the largest cards that fits on
+// a screen width columns wide and height rows high, above the
+// legend, or the one with the smallest cards if none of them fit.
+func FitLayout(width int, height int) Layout {
+	height -= LEGEND_ROWS
+	sizes := []CardSize{LargeCards, MediumCards, SmallCards}
+	layout := NewLayout(1, 1, sizes[len(sizes)-1])
+	for _, size := range sizes {
+		if fit := NewLayout(1, 1, size); fit.Right() < width && fit.Bottom() < height {
+			layout = fit
+			break
+		}
+	}
+	layout.screenHeight = height
+	return layout
+}
+
+// PileX returns the left of pile i.
+func (layout Layout) PileX(i int) int {
+	return layout.x + i*(layout.width+1+layout.gap)
+}
+
+// PilesTop returns the top of the part of the screen the piles are
+// drawn in. Everything above it stays put when the piles scroll.
+func (layout Layout) PilesTop() int {
+	top := layout.y + layout.height + 2
+	if _, _, _, y2 := layout.FoundationBox(0); top <= y2 {
+		top = y2 + 1
+	}
+	return top
+}
+
+// PileY returns the top of every pile, which is above PilesTop if
+// the piles have been scrolled.
+func (layout Layout) PileY() int {
+	return layout.PilesTop() - layout.scroll
+}
+
+// InfoX returns the left of the text shown next to the stock.
+func (layout Layout) InfoX() int {
+	return layout.x + layout.width + 2
+}
+
+// Right returns the right of the last pile.
+func (layout Layout) Right() int {
+	return layout.PileX(engine.NUM_PILES-1) + layout.width
+}
+
+// Bottom returns the bottom of a pile of FIT_HIDDEN face down cards
+// and FIT_VISIBLE face up cards.
+func (layout Layout) Bottom() int {
+	return layout.PilesTop() + FIT_HIDDEN*layout.hiddenStep +
+		(FIT_VISIBLE-1)*layout.visibleStep + layout.height
+}
+
+// FoundationBox returns the corners of the space for the ith full
+// stack. The spaces are lined up with the piles on the right, below
+// the text next to the stock, and are at least two rows high.
+func (layout Layout) FoundationBox(i int) (int, int, int, int) {
+	x := layout.PileX(engine.NUM_PILES - engine.NUM_STACKS + i)
+	y2 := layout.y + layout.height
+	if y2 < layout.y+3 {
+		y2 = layout.y + 3
+	}
+	return x, layout.y + 2, x + layout.width, y2
+}
+
+// PileHeight returns the height of the pile on the screen.
+// The unit is the y coordinate used by the Box struct.
+func (layout Layout) PileHeight(pile engine.Pile) int {
+	if pile.IsEmpty() {
+		// empty pile highlights should line up with the bottom
+		// card of the pile.
+		return layout.visibleStep
+	}
+	return pile.Hidden().Size()*layout.hiddenStep + pile.Visible().Size()*layout.visibleStep
+}
+
+// SelectedBox returns the corners of the box drawn around the cards
+// described by sel.
+func (layout Layout) SelectedBox(game Game, sel Selected) (int, int, int, int) {
+	var boxX int = layout.x
+	var boxY int = layout.y
+	if sel.y == 1 {
+		boxX = layout.PileX(sel.x)
+		distFromPileTop := layout.PileHeight(game.Pile(sel.x)) - (sel.numCards * layout.visibleStep)
+		boxY = layout.PileY() + distFromPileTop
+	}
+	return boxX, boxY, boxX + layout.width,
+		boxY + layout.height + ((sel.numCards - 1) * layout.visibleStep)
+}
+
+// HitTest returns what is drawn at x, y for game.
+func (layout Layout) HitTest(game Game, x int, y int) Hit {
+	if x >= layout.x && x <= layout.x+layout.width &&
+		y >= layout.y && y <= layout.y+layout.height {
+		return Hit{kind: HitStock}
+	}
+	for i := 0; i < engine.NUM_STACKS; i++ {
+		x1, y1, x2, y2 := layout.FoundationBox(i)
+		if x >= x1 && x <= x2 && y >= y1 && y <= y2 {
+			return Hit{kind: HitFoundation}
+		}
+	}
+	if y < layout.PilesTop() {
+		return Hit{}
+	}
+	for i := 0; i < engine.NUM_PILES; i++ {
+		if x < layout.PileX(i) || x > layout.PileX(i)+layout.width {
+			continue
+		}
+		pile := game.Pile(i)
+		visible := pile.Visible().Size()
+		row := y - layout.PileY()
+		hiddenRows := pile.Hidden().Size() * layout.hiddenStep
+		if visible == 0 {
+			if row <= hiddenRows+layout.height {
+				return Hit{HitPile, i, 0}
+			}
+			return Hit{}
+		}
+		if row < hiddenRows {
+			return Hit{HitPile, i, 0}
+		}
+		// Every face up card shows its top visibleStep rows, except
+		// the top card of the pile, which shows all of itself.
+		card := (row - hiddenRows) / layout.visibleStep
+		if card >= visible {
+			lastTop := hiddenRows + (visible-1)*layout.visibleStep
+			if row > lastTop+layout.height {
+				return Hit{}
+			}
+			card = visible - 1
+		}
+		return Hit{HitPile, i, visible - card}
+	}
+	return Hit{}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Scrolling
+///////////////////////////////////////////////////////////////////////////////
+
+// MaxScroll returns how far the piles of game can be scrolled up,
+// which is far enough for the bottom of the tallest pile to be on
+// the last row above the legend.
+func (layout Layout) MaxScroll(game Game) int {
+	if layout.screenHeight == 0 {
+		return 0
+	}
+	bottom := 0
+	for i := 0; i < engine.NUM_PILES; i++ {
+		_, _, _, y2 := layout.SelectedBox(game, Selected{i, 1, 1})
+		if y2+layout.scroll > bottom {
+			bottom = y2 + layout.scroll
+		}
+	}
+	if bottom < layout.screenHeight {
+		return 0
+	}
+	return bottom - (layout.screenHeight - 1)
+}
+
+// ScrollBy scrolls the piles of game up by rows, or down if rows is
+// negative, as far as they can go.
+func (layout *Layout) ScrollBy(game Game, rows int) {
+	layout.scroll += rows
+	layout.ClampScroll(game)
+}
+
+// ClampScroll makes sure the piles of game aren't scrolled further
+// than they can be, such as after cards are taken off a tall pile.
+func (layout *Layout) ClampScroll(game Game) {
+	if max := layout.MaxScroll(game); layout.scroll > max {
+		layout.scroll = max
+	}
+	if layout.scroll < 0 {
+		layout.scroll = 0
+	}
+}
+
+// KeepInView scrolls the piles of game as little as possible so
+// that the highlighted cards are on the screen. If they don't all
+// fit, the top of the highlight is shown.
+func (layout *Layout) KeepInView(game Game) {
+	if layout.screenHeight == 0 || game.highlighted.y != 1 {
+		layout.ClampScroll(game)
+		return
+	}
+	_, y1, _, y2 := layout.SelectedBox(game, game.highlighted)
+	if last := layout.screenHeight - 1; y2 > last {
+		layout.scroll += y2 - last
+		y1 -= y2 - last
+	}
+	if y1 < layout.PilesTop() {
+		layout.scroll -= layout.PilesTop() - y1
+	}
+	layout.ClampScroll(game)
+}
@@ -1,28 +1,37 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gdamore/tcell"
-)
 
-const NUM_PILES = 10
-const CARD_WIDTH = 11
-const CARD_HEIGHT = 7
+	"solitaire/engine"
+)
 
 ///////////////////////////////////////////////////////////////////////////////
 // Data Types
 ///////////////////////////////////////////////////////////////////////////////
 
-// Game contains all info about the current game
+// Game is a game being played in the terminal: the cards, from the
+// engine, along with the player's cursor, clock and messages.
 type Game struct {
-	deck        Deck // The remaining deck which has cards not yet on piles
-	piles       [NUM_PILES]Pile
-	highlighted Selected // which card the cursor is over
-	toMove      bool     // whether the user has cards selected that they might move
-	selected    Selected // which card(s) are selected
+	engine.Game
+	elapsed     time.Duration // time played before the clock was last started
+	started     time.Time     // when the clock was started, or zero if it is stopped
+	paused      bool          // whether the player has paused the game
+	message     string        // feedback for the player about their last action
+	hints       []engine.Move // suggested moves, best first
+	hintIndex   int           // which of hints is being shown
+	showHint    bool          // whether a hint is being shown
+	highlighted Selected      // which card the cursor is over
+	toMove      bool          // whether the user has cards selected that they might move
+	selected    Selected      // which card(s) are selected
 }
 
 // Selected is a description of cards currently selected/highlighted
@@ -33,38 +42,89 @@ type Selected struct {
 	numCards int // How many cards in a pile are highlighted
 }
 
+// Settings are the options the player picks before a game starts.
+type Settings struct {
+	difficulty engine.Difficulty
+	seed       int64 // the deal to play next, or 0 for a random deal
+	rules      engine.Rules
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // Main loops and setup
 ///////////////////////////////////////////////////////////////////////////////
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "solve" {
+		os.Exit(SolveCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(ReplayCommand(os.Args[2:]))
+	}
+
+	var settings Settings
+	suits := flag.Int("suits", int(engine.FourSuits), "number of suits to play with (1, 2 or 4)")
+	seed := flag.Int64("seed", 0, "deal number to play first (0 for a random deal)")
+	relaxed := flag.Bool("relaxed", false, "allow dealing from the stock while a pile is empty")
+	manualCollect := flag.Bool("manual-collect", false, "collect full stacks by hand instead of automatically")
+	themeName := flag.String("theme", "", "colours to draw the game with (classic, dark, high-contrast, monochrome or colour-blind)")
+	keysName := flag.String("keys", "", "keys to play with (arrows, vim or numeric)")
+	flag.Parse()
+	settings.difficulty = engine.Difficulty(*suits)
+	if !settings.difficulty.IsValid() {
+		fmt.Fprintln(os.Stderr, "-suits must be 1, 2 or 4")
+		os.Exit(2)
+	}
+	if *seed < 0 {
+		fmt.Fprintln(os.Stderr, "-seed must not be negative")
+		os.Exit(2)
+	}
+	if _, ok := FindTheme(*themeName); *themeName != "" && !ok {
+		fmt.Fprintln(os.Stderr, "-theme must be classic, dark, high-contrast, monochrome or colour-blind")
+		os.Exit(2)
+	}
+	if _, ok := FindKeymap(*keysName); *keysName != "" && !ok {
+		fmt.Fprintln(os.Stderr, "-keys must be arrows, vim or numeric")
+		os.Exit(2)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not read config file:", err)
+		os.Exit(1)
+	}
+	if *themeName == "" {
+		*themeName = config.Theme
+	}
+	if *keysName == "" {
+		*keysName = config.Keys
+	}
+	// The flag and the config file have both been checked already.
+	CurrentKeys, _ = MakeKeymap(*keysName, config.Bindings)
+	settings.seed = *seed
+	settings.rules.Relaxed = *relaxed
+	settings.rules.ManualCollect = *manualCollect
+
 	fmt.Println("start")
 
 	// Set up logging to the file "debug.log"
 	file, err := os.OpenFile("debug.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	defer file.Close()
 	log.SetOutput(file)
 
-	s, err := tcell.NewScreen()
+	s, err := NewScreen()
 	if err != nil {
-		log.Fatalf("Screen initialization failed: %+v", err)
-	}
-	// Screen setup based on
-	// https://github.com/gdamore/tcell/blob/master/_demos/boxes.go
-	if err = s.Init(); err != nil {
-		log.Fatalf("%v\n", err)
+		fmt.Fprintln(os.Stderr, "Screen initialization failed:", err)
 		os.Exit(1)
 	}
-
-	s.Clear()
-	s.Show()
-
+	defer FiniOnPanic(s)
+	UseTheme(s, *themeName)
 	for {
-		InstructionScreen(s)
-		PlayGame(s)
+		PlayGame(s, InstructionScreen(s, &settings))
+		// Only the first game is played with a chosen deal.
+		settings.seed = 0
 		wait := true
 		for wait {
 			ev := s.PollEvent()
@@ -82,13 +142,87 @@ func main() {
 	}
 }
 
-func InstructionScreen(s tcell.Screen) {
-	emitStr(s, 5, 0, 200, 200, tcell.StyleDefault.Bold(true), "Spider Solitaire")
-	emitStr(s, 5, 1, 200, 200, tcell.StyleDefault, "Use arrow keys to move and spacebar to select or move a card")
-	emitStr(s, 5, 2, 200, 200, tcell.StyleDefault, "Press ESC to exit")
-	emitStr(s, 5, 3, 200, 200, tcell.StyleDefault, "Press any key to continue")
+// NewScreen sets up the terminal screen. Returns an error if that
+// isn't possible.
+func NewScreen() (tcell.Screen, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	// Screen setup based on
+	// https://github.com/gdamore/tcell/blob/master/_demos/boxes.go
+	if err = s.Init(); err != nil {
+		return nil, err
+	}
+	s.EnableMouse()
+
+	s.Clear()
 	s.Show()
+	return s, nil
+}
+
+// FiniOnPanic restores the terminal if the program panics, so that
+// the panic is reported in a usable shell. It should be deferred
+// as soon as s has been set up.
+func FiniOnPanic(s tcell.Screen) {
+	if r := recover(); r != nil {
+		s.Fini()
+		panic(r)
+	}
+}
+
+// InstructionScreen shows how to play and lets the player change
+// settings before the game starts. Returns the game to play, which
+// is either a new game or the saved game if the player resumes it.
+func InstructionScreen(s tcell.Screen, settings *Settings) Game {
+	var problem string // what went wrong with the player's last choice
 	for {
+		s.Clear()
+		emitStr(s, 5, 0, 200, 200, tcell.StyleDefault.Bold(true), "Spider Solitaire")
+		line := 1
+		for _, help := range KeyHelp(CurrentKeys) {
+			emitStr(s, 5, line, 200, 200, tcell.StyleDefault, help)
+			line++
+		}
+		emitStr(s, 5, line, 200, 200, tcell.StyleDefault,
+			"Press 1, 2 or 4 to choose the number of suits (currently: "+
+				settings.difficulty.ToString()+")")
+		nextDeal := "a random deal"
+		if settings.seed != 0 {
+			nextDeal = "deal #" + strconv.FormatInt(settings.seed, 10)
+		}
+		emitStr(s, 5, line+1, 200, 200, tcell.StyleDefault,
+			"Press n to play a specific deal (currently: "+nextDeal+")")
+		emitStr(s, 5, line+2, 200, 200, tcell.StyleDefault, "Press t to see your statistics, and "+
+			CurrentKeys.Describe(ActionExport)+" in a game to export its moves")
+		dealRule := "only when no pile is empty"
+		if settings.rules.Relaxed {
+			dealRule = "any time"
+		}
+		emitStr(s, 5, line+3, 200, 200, tcell.StyleDefault,
+			"Press d to change when you can deal from the stock (currently: "+dealRule+")")
+		collectRule := "automatically"
+		if settings.rules.ManualCollect {
+			collectRule = "by hand, with " + CurrentKeys.Describe(ActionCollect) + " or by clicking the foundation"
+		}
+		emitStr(s, 5, line+4, 200, 200, tcell.StyleDefault,
+			"Press m to change how full stacks are collected (currently: "+collectRule+")")
+		emitStr(s, 5, line+5, 200, 200, tcell.StyleDefault, "Once every card is face up, press "+
+			CurrentKeys.Describe(ActionFinish)+" in a game to finish it automatically")
+		emitStr(s, 5, line+6, 200, 200, tcell.StyleDefault,
+			"Press o to change the colours here, or "+CurrentKeys.Describe(ActionTheme)+
+				" in a game (currently: "+CurrentTheme.name+")")
+		emitStr(s, 5, line+7, 200, 200, tcell.StyleDefault,
+			"Press k to change the keys you play with (currently: "+CurrentKeys.name+")")
+		line += 8
+		if HasSave() {
+			emitStr(s, 5, line, 200, 200, tcell.StyleDefault, "Press c to continue your saved game")
+			line++
+		}
+		emitStr(s, 5, line, 200, 200, tcell.StyleDefault, "Press any other key to start a new game")
+		emitStr(s, 5, line+1, 200, 200, CurrentTheme.error, problem)
+		s.Show()
+
 		ev := s.PollEvent()
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
@@ -96,55 +230,310 @@ func InstructionScreen(s tcell.Screen) {
 			case tcell.KeyEscape:
 				s.Fini()
 				os.Exit(0)
+			case tcell.KeyRune:
+				switch ev.Rune() {
+				case '1':
+					settings.difficulty = engine.OneSuit
+				case '2':
+					settings.difficulty = engine.TwoSuits
+				case '4':
+					settings.difficulty = engine.FourSuits
+				case 'n':
+					if seed, ok := PromptNumber(s, 5, line+2, "Play deal #"); ok {
+						settings.seed = seed
+					}
+				case 'c':
+					if game, err := LoadGame(); err != nil {
+						problem = "Could not load saved game: " + err.Error()
+					} else {
+						return game
+					}
+				case 'd':
+					settings.rules.Relaxed = !settings.rules.Relaxed
+				case 'm':
+					settings.rules.ManualCollect = !settings.rules.ManualCollect
+				case 't':
+					StatsScreen(s)
+				case 'o':
+					problem = ""
+					if err := SwitchTheme(s, CurrentTheme.name); err != nil {
+						problem = "Could not save the theme: " + err.Error()
+					}
+				case 'k':
+					problem = ""
+					if err := SwitchKeys(CurrentKeys.name); err != nil {
+						problem = "Could not save the keys: " + err.Error()
+					}
+				default:
+					return startNewGame(*settings)
+				}
 			default:
-				return
+				return startNewGame(*settings)
 			}
 		}
 	}
 }
 
-// PlayGame has the main loop for the game of solitaire.
-func PlayGame(s tcell.Screen) {
-	var game Game = Deal()
+// startNewGame deals a new game, counting the saved game as
+// abandoned since the player chose not to resume it.
+func startNewGame(settings Settings) Game {
+	if err := AbandonSave(); err != nil {
+		log.Print("Could not record abandoned game: ", err)
+	}
+	return NewGame(settings)
+}
+
+// PromptNumber asks the user to type in a positive number after
+// label, at x, y. Returns false if the user pressed ESC instead.
+func PromptNumber(s tcell.Screen, x int, y int, label string) (int64, bool) {
+	var digits string
+	for {
+		emitStr(s, x, y, 200, y, tcell.StyleDefault, label+digits+"_ ")
+		s.Show()
 
+		ev := s.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return 0, false
+			case tcell.KeyBackspace, tcell.KeyBackspace2:
+				if len(digits) > 0 {
+					digits = digits[:len(digits)-1]
+				}
+			case tcell.KeyEnter:
+				number, err := strconv.ParseInt(digits, 10, 64)
+				if err == nil && number > 0 {
+					return number, true
+				}
+			case tcell.KeyRune:
+				if ev.Rune() >= '0' && ev.Rune() <= '9' && len(digits) < 18 {
+					digits += string(ev.Rune())
+				}
+			}
+		}
+	}
+}
+
+// PlayGame has the main loop for the game of solitaire.
+// When the player leaves with ESC the game is saved so that
+// it can be resumed later. If something goes wrong while the
+// player is moving, the error is shown and the game carries on.
+func PlayGame(s tcell.Screen, game Game) {
 	var gameWon bool = false
+	var pressed bool = false // whether the mouse button is down
+	var err error            // what went wrong with the player's last action
+	layout := FitLayout(s.Size())
+	lastInput := time.Now()
+	game.StartClock()
+
+	// Redraw every so often, so that the clock keeps going even
+	// while the player is thinking.
+	stop := make(chan struct{})
+	defer close(stop)
+	go Tick(s, TICK_INTERVAL, stop)
 
 	// for loop based on https://github.com/gdamore/tcell/blob/master/_demos/boxes.go
 	for {
+		if err != nil {
+			log.Print("Error while playing: ", err)
+			game.message = "Something went wrong: " + err.Error()
+			game.toMove = false
+			err = nil
+		}
+		layout.ClampScroll(game)
 		s.Clear()
-		game.Render(s, 1, 1)
+		game.Render(s, layout)
+		RenderLegend(s, layout, CurrentKeys)
 		s.Show()
 
 		if gameWon {
-			RenderGameWon(s, 1, 1)
+			game.StopClock()
+			if err := RecordGame(game, Won); err != nil {
+				log.Print("Could not record won game: ", err)
+			}
+			DeleteSave()
+			RenderGameWon(s, layout, game)
 			s.Show()
 			return
 		}
 
+		if game.IsStuck() {
+			game.StopClock()
+			choice := AskWhenStuck(s, game)
+			if choice == StuckUndo {
+				game.Undo()
+				game.StartClock()
+				continue
+			}
+			// Giving up on the deal counts as losing it.
+			if err := RecordGame(game, Lost); err != nil {
+				log.Print("Could not record lost game: ", err)
+			}
+			DeleteSave()
+			settings := Settings{difficulty: game.Difficulty(), rules: game.Rules()}
+			switch choice {
+			case StuckLeave:
+				s.Fini()
+				os.Exit(0)
+			case StuckRestart:
+				settings.seed = game.Seed()
+			}
+			game = NewGame(settings)
+			game.StartClock()
+			continue
+		}
+
 		ev := s.PollEvent()
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
-			switch ev.Key() {
-			case tcell.KeyEscape:
+			lastInput = time.Now()
+			if game.paused && ev.Key() != tcell.KeyEscape {
+				game.Resume()
+				continue
+			}
+			game.message = ""
+			if ev.Key() == tcell.KeyEscape {
+				err := SaveGame(game)
 				s.Fini()
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Could not save game:", err)
+					os.Exit(1)
+				}
 				os.Exit(0)
-			case tcell.KeyCtrlL:
+			}
+			binding := CurrentKeys.Lookup(ev)
+			if binding.action != ActionHint {
+				game.showHint = false
+			}
+			switch binding.action {
+			case ActionRedraw:
 				s.Sync()
-			case tcell.KeyUp, tcell.KeyDown: // up and down do same thing
+			case ActionScrollUp:
+				layout.ScrollBy(game, -layout.screenHeight/2)
+			case ActionScrollDown:
+				layout.ScrollBy(game, layout.screenHeight/2)
+			case ActionUp:
 				game.Up()
-			case tcell.KeyRight:
+			case ActionDown:
+				game.Down()
+			case ActionCancel:
+				game.Cancel()
+			case ActionRight:
 				game.Right()
-			case tcell.KeyLeft:
+			case ActionLeft:
 				game.Left()
-			case tcell.KeyEnter:
-				gameWon = game.Select()
-			case tcell.KeyRune:
-				if ev.Rune() == ' ' {
-					gameWon = game.Select()
+			case ActionSelect:
+				gameWon, err = game.Select()
+			case ActionPile:
+				gameWon, err = game.GoToPile(binding.pile)
+			case ActionDeal:
+				gameWon, err = game.DealFromStock()
+			case ActionUndo:
+				game.Undo()
+			case ActionRedo:
+				game.Redo()
+				gameWon = game.CheckWon()
+			case ActionRun:
+				game.HighlightRun()
+			case ActionCollect:
+				game.toMove = false
+				if !game.Collect() {
+					game.message = "There are no full stacks to collect"
+				}
+				gameWon = game.CheckWon()
+			case ActionFinish:
+				gameWon, err = AutoFinish(s, layout, &game)
+			case ActionHint:
+				game.NextHint()
+			case ActionPause:
+				game.Pause()
+			case ActionTheme:
+				if err := SwitchTheme(s, CurrentTheme.name); err != nil {
+					game.message = "Could not save the theme: " + err.Error()
+				} else {
+					game.message = "Colours changed to the " + CurrentTheme.name + " theme"
+				}
+			case ActionPlayDeal:
+				seed, ok := PromptNumber(s, layout.InfoX(), layout.y+1, "Play deal #")
+				if !ok {
+					break
+				}
+				// The game being played is replaced by a different one.
+				game.StopClock()
+				if err := RecordGame(game, Abandoned); err != nil {
+					log.Print("Could not record abandoned game: ", err)
+				}
+				DeleteSave()
+				game = NewGame(Settings{difficulty: game.Difficulty(), seed: seed, rules: game.Rules()})
+				game.StartClock()
+				game.message = fmt.Sprintf("Playing deal #%d", seed)
+			case ActionExport:
+				if name, err := ExportHistory(game); err != nil {
+					game.message = "Could not export move history: " + err.Error()
+				} else {
+					game.message = "Move history exported to " + name
+				}
+			case ActionSave:
+				if err := SaveGame(game); err != nil {
+					game.message = "Could not save game: " + err.Error()
+				} else {
+					game.message = "Game saved"
+				}
+			case ActionLoad:
+				if loaded, err := LoadGame(); err != nil {
+					game.message = "Could not load saved game: " + err.Error()
+				} else {
+					if loaded.Seed() != game.Seed() || loaded.Difficulty() != game.Difficulty() {
+						// The game being played is replaced by a different one.
+						game.StopClock()
+						if err := RecordGame(game, Abandoned); err != nil {
+							log.Print("Could not record abandoned game: ", err)
+						}
+					}
+					game = loaded
+					game.StartClock()
+					game.message = "Game loaded"
+				}
+			}
+			if binding.action != ActionScrollUp && binding.action != ActionScrollDown {
+				layout.KeepInView(game)
+			}
+		case *tcell.EventMouse:
+			lastInput = time.Now()
+			x, y := ev.Position()
+			hit := layout.HitTest(game, x, y)
+			if game.paused {
+				if ev.Buttons()&tcell.Button1 != 0 {
+					game.Resume()
+				}
+			} else if ev.Buttons()&tcell.WheelUp != 0 {
+				layout.ScrollBy(game, -SCROLL_STEP)
+			} else if ev.Buttons()&tcell.WheelDown != 0 {
+				layout.ScrollBy(game, SCROLL_STEP)
+			} else if ev.Buttons()&tcell.Button1 != 0 {
+				if !pressed {
+					pressed = true
+					game.message = ""
+					game.showHint = false
+					gameWon, err = game.Press(hit)
 				}
+			} else if pressed && ev.Buttons() == tcell.ButtonNone {
+				pressed = false
+				gameWon, err = game.Release(hit)
+			}
+		case *tcell.EventInterrupt:
+			if !game.paused && time.Since(lastInput) >= IDLE_PAUSE {
+				game.Pause()
 			}
 		case *tcell.EventResize:
 			s.Sync()
+			layout = FitLayout(s.Size())
+			layout.KeepInView(game)
+		}
+		if game.message == "" && game.CanAutoFinish() {
+			game.message = "Every card is face up, press " + CurrentKeys.Describe(ActionFinish) +
+				" to finish the game automatically"
 		}
 	}
 }
@@ -153,141 +542,156 @@ func PlayGame(s tcell.Screen) {
 // Game state modification functions
 ///////////////////////////////////////////////////////////////////////////////
 
-// CreateDeck creates the deck with all cards. The Deck
-// contains 2 full standard playing card decks (without
-// jokers).
-func CreateDeck() Deck {
-	var deck Deck = NewDeck(52)
-	// two full deck of cards
-	for i := 0; i < 2; i++ {
-		for s := Spades; s <= Diamonds; s++ {
-			for v := Ace; v <= King; v++ {
-				deck.Add(Card{s, v})
-			}
-		}
+// NewGame deals a new game with the difficulty, deal number and
+// rules chosen in settings, picking a random deal if none was chosen.
+func NewGame(settings Settings) Game {
+	seed := settings.seed
+	if seed == 0 {
+		seed = engine.NewSeed()
 	}
-	deck.Shuffle()
-	return deck
+	return Wrap(engine.NewGame(settings.difficulty, seed, settings.rules))
 }
 
-// Deal creates all status needed to start the game,
-// and returns it in the Game struct.
-func Deal() Game {
-	var game Game
-	var deck Deck = CreateDeck()
-	// first four piles get 6 cards
-	for p := 0; p < 4; p++ {
-		for c := 0; c < 5; c++ {
-			var card Card = deck.Draw()
-			game.piles[p].invisible.Add(card)
-		}
-	}
-	// last for get 5 cards
-	for p := 4; p < NUM_PILES; p++ {
-		for c := 0; c < 4; c++ {
-			var card Card = deck.Draw()
-			game.piles[p].invisible.Add(card)
-		}
-	}
-	// the top card is visible
-	for p := 0; p < NUM_PILES; p++ {
-		var card Card = deck.Draw()
-		game.piles[p].visible.Add(card)
-	}
-
-	game.deck = deck
-	game.highlighted.numCards = 1
-
-	return game
+// Wrap returns a Game for playing the cards of game, with the
+// cursor on the stock.
+func Wrap(game engine.Game) Game {
+	return Game{Game: game, highlighted: Selected{0, 0, 1}}
 }
 
-// MoreCards deals another layer of cards onto the piles from the deck
-func (game *Game) MoreCards() {
-	for i := 0; i < NUM_PILES; i++ {
-		game.piles[i].visible.Add(game.deck.Draw())
+// ErrNoPileHighlighted is returned when cards are moved while the
+// cursor is on the stock rather than a pile.
+var ErrNoPileHighlighted = errors.New("no pile is highlighted")
+
+// MoveCards attempts to move the selected cards to the highlighted
+// pile. Returns engine.ErrIllegalMove if the rules don't allow it.
+func (game *Game) MoveCards() error {
+	if game.highlighted.y != 1 {
+		return ErrNoPileHighlighted
 	}
+	return game.MoveRun(game.selected.x, game.selected.numCards, game.highlighted.x)
 }
 
-// MoveCards attempts to move the selected cards to the highlighted pile.
-func (game *Game) MoveCards() {
-	Assert(game.highlighted.y == 1, "game.highlighted.y == 1")
-	var topMovedCard Card = game.piles[game.selected.x].PeekNthCard(game.selected.numCards - 1)
-	var cardMovedOnto Card = game.piles[game.highlighted.x].PeekNthCard(0)
-	var canMove bool = (topMovedCard.value == cardMovedOnto.value-1) ||
-		game.piles[game.highlighted.x].IsEmpty()
-	if canMove {
-		topNCards := game.piles[game.selected.x].GetTopNCards(game.selected.numCards)
-		for _, v := range topNCards {
-			game.piles[game.highlighted.x].visible.Add(v)
-		}
+// Undo takes back the last action, and drops any selection the
+// player had. Returns false if there was nothing to undo.
+func (game *Game) Undo() bool {
+	if !game.Game.Undo() {
+		return false
 	}
+	game.toMove = false
+	game.highlighted.numCards = 1
+	return true
 }
 
-// IsFullStack returns true if the first 13 cards are a full stack
-func IsFullStack(cards []Card) bool {
-	log.Print("IsFullStack!", len(cards), NUM_VALUES)
-	if len(cards) < NUM_VALUES {
-		//fmt.Println("length: ", len(cards), NUM_VALUES)
+// Redo does the last action that was undone again, and drops any
+// selection the player had. Returns false if there was nothing to
+// redo.
+func (game *Game) Redo() bool {
+	if !game.Game.Redo() {
 		return false
 	}
+	game.toMove = false
+	game.highlighted.numCards = 1
+	return true
+}
 
-	stopVal := 0
-	if len(cards)-NUM_VALUES > 0 {
-		stopVal = len(cards) - NUM_VALUES
+// StartClock starts counting the time spent playing game.
+func (game *Game) StartClock() {
+	if game.started.IsZero() {
+		game.started = time.Now()
 	}
+}
 
-	currValue := Ace
-	for i := len(cards) - 1; i >= stopVal; i-- {
+// StopClock stops counting the time spent playing game.
+func (game *Game) StopClock() {
+	game.elapsed = game.Elapsed()
+	game.started = time.Time{}
+}
 
-		if cards[i].value != currValue {
-			return false
-		}
-		currValue++
-	}
-	return true
+// Pause stops the clock and hides the cards until the player
+// carries on with Resume.
+func (game *Game) Pause() {
+	game.StopClock()
+	game.paused = true
+	game.toMove = false
+	game.showHint = false
 }
 
-// CheckStacks looks for piles that contain a full stack of
-// cards, and deletes off the full stacks.
-func (game *Game) CheckStacks() {
-	log.Print("Checking stacks!")
-	for i := 0; i < NUM_PILES; i++ {
-		if IsFullStack(game.piles[i].visible.cards) {
-			game.piles[i].GetTopNCards(NUM_VALUES)
-		}
-	}
+// Resume carries on with a paused game.
+func (game *Game) Resume() {
+	game.paused = false
+	game.message = ""
+	game.StartClock()
 }
 
-// CheckWon checks if there are no more cards and so the user
-// has won. Returns true is the user has won, and false otherwise.
-func (game *Game) CheckWon() bool {
-	for i := 0; i < NUM_PILES; i++ {
-		if !game.piles[i].IsEmpty() {
-			return false
-		}
+// Elapsed returns the total time spent playing game.
+func (game Game) Elapsed() time.Duration {
+	if game.started.IsZero() {
+		return game.elapsed
 	}
-	// if theres nothing more in the deck or the piles, then we won
-	return game.deck.IsEmpty()
+	return game.elapsed + time.Since(game.started)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // Player move functions
 ///////////////////////////////////////////////////////////////////////////////
 
-// Up makes changes for the user pressing the up or down arrows.
-// Moves between deck and piles.
+// Up makes changes for the user pressing the up arrow.
+// Highlights one more card of the highlighted pile if the
+// cards can be moved together, and otherwise moves up to
+// the deck.
 func (game *Game) Up() {
-	game.highlighted.y = (game.highlighted.y + 1) % 2
+	if game.highlighted.y == 1 &&
+		game.Pile(game.highlighted.x).TopNMovable(game.highlighted.numCards+1) {
+		game.setHighlightedCards(game.highlighted.numCards + 1)
+		return
+	}
+	game.highlighted.y = 0
 	game.highlighted.numCards = 1
 }
 
+// Down makes changes for the user pressing the down arrow.
+// Highlights one less card of the highlighted pile, or moves
+// down from the deck to the piles.
+func (game *Game) Down() {
+	if game.highlighted.y == 0 {
+		// Any selected cards stay selected as they were.
+		game.highlighted.y = 1
+		game.highlighted.numCards = 1
+	} else if game.highlighted.numCards > 1 {
+		game.setHighlightedCards(game.highlighted.numCards - 1)
+	}
+}
+
+// HighlightRun highlights the longest run of cards on the
+// highlighted pile that can be moved together.
+func (game *Game) HighlightRun() {
+	if game.highlighted.y == 1 {
+		game.setHighlightedCards(game.Pile(game.highlighted.x).MovableRun())
+	}
+}
+
+// Cancel lets go of the selected cards without moving them.
+func (game *Game) Cancel() {
+	game.toMove = false
+}
+
+// setHighlightedCards highlights the top n cards of the
+// highlighted pile. If cards from that pile are selected,
+// the same cards become selected.
+func (game *Game) setHighlightedCards(n int) {
+	game.highlighted.numCards = n
+	if game.toMove && game.selected.x == game.highlighted.x {
+		game.selected.numCards = n
+	}
+}
+
 // Left makes changes for the user pressing the left arrow.
 // Moves highlighted cursor one to the left.
 func (game *Game) Left() {
 	if game.highlighted.x == 0 {
-		game.highlighted.x = NUM_PILES - 1
+		game.highlighted.x = engine.NUM_PILES - 1
 	} else {
-		game.highlighted.x = (game.highlighted.x - 1) % NUM_PILES
+		game.highlighted.x = (game.highlighted.x - 1) % engine.NUM_PILES
 	}
 	game.highlighted.numCards = 1
 }
@@ -295,7 +699,7 @@ func (game *Game) Left() {
 // Right makes the changes for the user pressing the right arrow.
 // Moves highlighted cursor one to the right.
 func (game *Game) Right() {
-	game.highlighted.x = (game.highlighted.x + 1) % NUM_PILES
+	game.highlighted.x = (game.highlighted.x + 1) % engine.NUM_PILES
 	game.highlighted.numCards = 1
 }
 
@@ -306,7 +710,9 @@ func (game *Game) Right() {
 // When a pile is highlighted and cards from it are
 // selected, Select selects one more card from that pile,
 // if allowed.
-func (game *Game) Select() bool {
+// Returns true if the game has been won, and an error if
+// something went wrong other than the move not being allowed.
+func (game *Game) Select() (bool, error) {
 	if game.highlighted.y == 1 {
 		// The user has a pile highlighted
 		if !game.toMove {
@@ -325,13 +731,18 @@ func (game *Game) Select() bool {
 				// try to select one more card from that pile. You can only
 				// select multiple cards together if they are all moveable
 				// together.
-				if game.piles[game.selected.x].TopNMovable(game.selected.numCards + 1) {
+				if game.Pile(game.selected.x).TopNMovable(game.selected.numCards + 1) {
 					game.selected.numCards++
 				}
+				game.highlighted.numCards = game.selected.numCards
 			} else {
 				// Try to move the selected cards to the new pile
-				game.MoveCards()
+				err := game.MoveCards()
 				game.toMove = false
+				game.highlighted.numCards = 1
+				if err != nil && err != engine.ErrIllegalMove {
+					return false, err
+				}
 				// // The user is trying to select a pile other than what
 				// // has been selected, so we change the selection to be
 				// // whatever the user has highlighted.
@@ -344,75 +755,167 @@ func (game *Game) Select() bool {
 	} else {
 		// the user has pressed enter while the deck is highlighted.
 		// Get more cards from the deck.
-		game.MoreCards()
+		err := game.Deal()
+		if err == engine.ErrStockEmpty || err == engine.ErrPileEmpty {
+			game.message = "Can't deal: " + err.Error()
+		} else if err != nil {
+			return false, err
+		}
 	}
 
 	// The player pressing enter can trigger any given pile to
 	// now have a full stack.
-	game.CheckStacks()
-	return game.CheckWon()
+	if game.Rules().ManualCollect && game.HasFullStack() {
+		game.message = "Press " + CurrentKeys.Describe(ActionCollect) + " to collect the full stack"
+	}
+	return game.CheckWon(), nil
+}
+
+// DealFromStock moves the cursor to the stock and deals more cards
+// from it, letting go of any selected cards. Returns true if the
+// game has been won, and an error if something went wrong.
+func (game *Game) DealFromStock() (bool, error) {
+	game.toMove = false
+	game.highlighted = Selected{0, 0, 1}
+	return game.Select()
+}
+
+// GoToPile makes the changes for the user pressing the key for pile.
+// If no cards are selected, the longest run of cards on pile that
+// can be moved together is selected. If cards from another pile are
+// selected, as many of them as fit on pile are moved there, and if
+// cards from pile itself are selected they are let go of.
+// Returns true if the game has been won, and an error if something
+// went wrong.
+func (game *Game) GoToPile(pile int) (bool, error) {
+	if game.toMove && pile != game.selected.x {
+		// Only part of the run may fit on pile.
+		for n := game.selected.numCards; n > 0; n-- {
+			if game.CanMoveRun(game.selected.x, n, pile) {
+				game.selected.numCards = n
+				break
+			}
+		}
+		return game.MoveTo(pile)
+	}
+	wasSelected := game.toMove
+	game.toMove = false
+	game.highlighted = Selected{pile, 1, 1}
+	if wasSelected {
+		return false, nil
+	}
+	if game.Pile(pile).IsEmpty() {
+		game.message = fmt.Sprintf("Pile %d is empty", pile+1)
+		return false, nil
+	}
+	game.HighlightRun()
+	game.selected = game.highlighted
+	game.toMove = true
+	return false, nil
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // Graphics
 ///////////////////////////////////////////////////////////////////////////////
 
-// Render renders the full current game
-func (game Game) Render(s tcell.Screen, x int, y int) {
-	if !game.deck.IsEmpty() {
-		// game.deck.cards[0].RenderFlipped(s, x, y, (game.highlighted.y == 0))
-		game.deck.cards[0].RenderFlipped(s, x, y)
+// Render renders the full current game, in the positions given
+// by layout.
+func (game Game) Render(s tcell.Screen, layout Layout) {
+	if game.paused {
+		game.RenderHeader(s, layout)
+		RenderPaused(s, layout)
+		return
+	}
+	for i := 0; i < engine.NUM_PILES; i++ {
+		RenderPile(s, layout, game.Pile(i), layout.PileX(i), layout.PileY())
 	}
-	for i := 0; i < NUM_PILES; i++ {
-		game.piles[i].Render(s, x+(CARD_WIDTH+2)*i, y+CARD_HEIGHT+2)
+	if game.showHint {
+		hint := game.hints[game.hintIndex]
+		game.RenderSelected(s, layout, Selected{hint.To, 1, 1}, CurrentTheme.hint)
+		game.RenderSelected(s, layout, Selected{hint.From, 1, hint.Count}, CurrentTheme.hint)
 	}
-	style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
 
-	hglt := game.highlighted
-	var higBoxX int = 1
-	var higBoxY int = 1
-	if hglt.y == 1 {
-		higBoxX = x + hglt.x*(CARD_WIDTH+2)
-		distFromPileTop := game.piles[hglt.x].Height() - hglt.numCards*2
-		higBoxY = y + CARD_HEIGHT + 2 + distFromPileTop
+	if game.highlighted.y == 1 {
+		game.RenderSelected(s, layout, game.highlighted, CurrentTheme.highlight)
 	}
-	var higBox Box = Box{s, higBoxX, higBoxY,
-		higBoxX + CARD_WIDTH, higBoxY + CARD_HEIGHT + ((hglt.numCards - 1) * 2),
-		style, "", true}
-	higBox.Draw()
 
 	if game.toMove {
-		sel := game.selected
-		style = tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorYellow)
-		var selBoxX int = 1
-		var selBoxY int = 1
-		if sel.y == 1 {
-			selBoxX = x + sel.x*(CARD_WIDTH+2)
-			distFromPileTop := game.piles[sel.x].Height() - (sel.numCards * 2)
-			selBoxY = y + CARD_HEIGHT + 2 + distFromPileTop
-		}
-		var selBox Box = Box{s, selBoxX, selBoxY,
-			selBoxX + CARD_WIDTH, selBoxY + CARD_HEIGHT + ((sel.numCards - 1) * 2),
-			style, "", true}
-		selBox.Draw()
+		game.RenderSelected(s, layout, game.selected, CurrentTheme.selected)
+	}
+
+	// The piles scroll underneath everything above them.
+	ClearRows(s, 0, layout.PilesTop()-1)
+	game.RenderHeader(s, layout)
+	if game.highlighted.y == 0 {
+		game.RenderSelected(s, layout, game.highlighted, CurrentTheme.highlight)
 	}
+	game.RenderScrollMarks(s, layout)
 }
 
-// RenderGameWon renders the message that the game was won.
-func RenderGameWon(s tcell.Screen, x int, y int) {
-	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorGreen)
-	var box Box = Box{s, x, y,
-		x + 10*(CARD_WIDTH+1), y + 2*CARD_HEIGHT + 2,
-		style, "You won! Press ESC to leave, and enter to restart", false}
-	box.Draw()
+// RenderHeader renders the parts of game drawn above the piles: the
+// stock, the foundation, the status bar and the message for the
+// player.
+func (game Game) RenderHeader(s tcell.Screen, layout Layout) {
+	game.RenderStock(s, layout)
+	game.RenderFoundation(s, layout)
+	game.RenderStatus(s, layout)
+	emitStr(s, layout.InfoX(), layout.y+1, layout.Right(), layout.y+1,
+		tcell.StyleDefault, game.message)
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// Utilities
-///////////////////////////////////////////////////////////////////////////////
+// RenderScrollMarks renders arrows to the right of the piles when
+// there is more of them to see by scrolling up or down.
+func (game Game) RenderScrollMarks(s tcell.Screen, layout Layout) {
+	up, down := '▲', '▼'
+	if !s.CanDisplay(up, false) || !s.CanDisplay(down, false) {
+		up, down = '^', 'v'
+	}
+	if layout.scroll > 0 {
+		s.SetContent(layout.Right()+1, layout.PilesTop(), up, nil, tcell.StyleDefault)
+	}
+	if layout.scroll < layout.MaxScroll(game) {
+		s.SetContent(layout.Right()+1, layout.screenHeight-1, down, nil, tcell.StyleDefault)
+	}
+}
 
-func Assert(b bool, s string) {
-	if !b {
-		log.Fatalf(s)
+// RenderStock renders the deck as a face down card showing how many
+// deals are left, or as an empty space once it has run out.
+func (game Game) RenderStock(s tcell.Screen, layout Layout) {
+	x2, y2 := layout.x+layout.width, layout.y+layout.height
+	if game.Stock().IsEmpty() {
+		box := Box{s, layout.x, layout.y, x2, y2, tcell.StyleDefault, "no deals", true}
+		box.Draw()
+		return
+	}
+	text := fmt.Sprintf(" %d deals ", game.DealsLeft())
+	if game.DealsLeft() == 1 {
+		text = " 1 deal "
 	}
+	if len(text) >= layout.width {
+		// Small cards only have room for the number.
+		text = fmt.Sprint(game.DealsLeft())
+	}
+	RenderCardBack(s, layout, layout.x, layout.y)
+	y := layout.y + layout.height/2
+	emitStr(s, layout.x+1, y, x2-1, y, CurrentTheme.back, text)
+}
+
+// RenderSelected draws a box in style around the cards described by
+// sel.
+func (game Game) RenderSelected(s tcell.Screen, layout Layout, sel Selected, style tcell.Style) {
+	x1, y1, x2, y2 := layout.SelectedBox(game, sel)
+	var box Box = Box{s, x1, y1, x2, y2, style, "", true}
+	box.Draw()
+}
+
+// RenderGameWon renders the message that game was won, over the top
+// of the game drawn with layout.
+func RenderGameWon(s tcell.Screen, layout Layout, game Game) {
+	style := CurrentTheme.won
+	text := fmt.Sprintf("You won with a score of %d in %d moves! "+
+		"Press ESC to leave, and enter to restart", game.Score(), game.Moves())
+	var box Box = Box{s, layout.x, layout.y,
+		layout.Right(), layout.PilesTop() + layout.height,
+		style, text, false}
+	box.Draw()
 }
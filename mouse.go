@@ -0,0 +1,60 @@
+package main
+
+///////////////////////////////////////////////////////////////////////////////
+// Mouse functions
+///////////////////////////////////////////////////////////////////////////////
+
+// Press handles the player pressing the mouse button over hit.
+// Pressing the stock deals more cards, pressing a face up card
+// selects the run from that card to the top of its pile, and
+// pressing another pile while cards are selected moves them there.
+// Pressing the foundation collects any full stacks.
+// Returns true if the game has been won, and an error if
+// something went wrong.
+func (game *Game) Press(hit Hit) (bool, error) {
+	switch hit.kind {
+	case HitStock:
+		return game.DealFromStock()
+	case HitFoundation:
+		game.toMove = false
+		game.Collect()
+		return game.CheckWon(), nil
+	case HitPile:
+		if game.toMove && hit.pile != game.selected.x {
+			return game.MoveTo(hit.pile)
+		}
+		run := Selected{hit.pile, 1, hit.numCards}
+		if game.toMove && game.selected == run {
+			// Pressing the selected cards again lets go of them.
+			game.toMove = false
+			return false, nil
+		}
+		game.highlighted = Selected{hit.pile, 1, 1}
+		game.toMove = false
+		if hit.numCards > 0 && game.Pile(hit.pile).TopNMovable(hit.numCards) {
+			game.highlighted = run
+			game.selected = run
+			game.toMove = true
+		}
+	}
+	return false, nil
+}
+
+// Release handles the player letting go of the mouse button over
+// hit. If they dragged selected cards to another pile, the cards
+// are moved there. Returns true if the game has been won, and an
+// error if something went wrong.
+func (game *Game) Release(hit Hit) (bool, error) {
+	if !game.toMove || hit.kind != HitPile || hit.pile == game.selected.x {
+		return false, nil
+	}
+	return game.MoveTo(hit.pile)
+}
+
+// MoveTo tries to move the selected cards onto pile, and highlights
+// pile. Returns true if the game has been won, and an error if
+// something went wrong.
+func (game *Game) MoveTo(pile int) (bool, error) {
+	game.highlighted = Selected{pile, 1, 1}
+	return game.Select()
+}
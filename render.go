@@ -0,0 +1,106 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Cards
+///////////////////////////////////////////////////////////////////////////////
+
+// RenderCard renders a single face-up card on the terminal screen, with the
+// upper-left corner at point x, y and the size given by layout. The card's
+// label is in the top left corner, so that it can be read when other cards
+// overlap the card, and again in the bottom right corner, with the suit in
+// the middle. Compact cards have their label in their top edge instead.
+func RenderCard(s tcell.Screen, layout Layout, card engine.Card, x int, y int) {
+	x2, y2 := x+layout.width, y+layout.height
+	box1 := Box{s, x, y, x2, y2, CurrentTheme.face, "", false}
+	box1.Draw()
+
+	style := SuitStyle(card.Suit())
+	label := CardLabel(s, card)
+	s.SetContent(x+layout.width/2, y+layout.height/2, SuitRune(s, card.Suit()), nil, style)
+	if layout.compact {
+		emitStr(s, x+1, y, x2-1, y, style, label)
+		return
+	}
+	labelX := x2 - utf8.RuneCountInString(label)
+	emitStr(s, x+1, y+1, x2-1, y+1, style, label)
+	emitStr(s, labelX, y2-1, x2, y2-1, style, label)
+}
+
+// RenderCardBack renders a face-down card on the terminal screen, with
+// the upper-left corner at point x, y and the size given by layout.
+func RenderCardBack(s tcell.Screen, layout Layout, x int, y int) {
+	box1 := Box{s, x, y, x + layout.width, y + layout.height,
+		CurrentTheme.back, "", false}
+	box1.Draw()
+
+	pattern := tcell.RuneCkBoard
+	if !s.CanDisplay(pattern, true) {
+		pattern = '#'
+	}
+	for row := y + 1; row < y+layout.height; row++ {
+		for col := x + 1; col < x+layout.width; col++ {
+			s.SetContent(col, row, pattern, nil, CurrentTheme.backPattern)
+		}
+	}
+}
+
+// CardLabel returns the rank and suit of card as shown in its
+// corners, such as "Q♦", or "QD" if s can't show the suit symbols.
+func CardLabel(s tcell.Screen, card engine.Card) string {
+	return card.Value().Label() + string(SuitRune(s, card.Suit()))
+}
+
+// SuitRune returns the symbol of suit, or the first letter of its
+// name if s can't show the symbol.
+func SuitRune(s tcell.Screen, suit engine.CardSuit) rune {
+	if s.CanDisplay(suit.Symbol(), false) {
+		return suit.Symbol()
+	}
+	return suit.Letter()
+}
+
+// SuitStyle returns the style of the writing on a card of suit in
+// the current theme.
+func SuitStyle(suit engine.CardSuit) tcell.Style {
+	return CurrentTheme.suits[suit]
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Piles
+///////////////////////////////////////////////////////////////////////////////
+
+// RenderPile renders a pile on the terminal, with the face down cards
+// first and the face up cards overlapping them, spaced out as given by
+// layout.
+func RenderPile(s tcell.Screen, layout Layout, pile engine.Pile, x int, y int) {
+	hidden := pile.Hidden()
+	for i := 0; i < hidden.Size(); i++ {
+		RenderCardBack(s, layout, x, y+layout.hiddenStep*i)
+	}
+	y += layout.hiddenStep * hidden.Size()
+	for i, card := range pile.Visible().Cards() {
+		RenderCard(s, layout, card, x, y+layout.visibleStep*i)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Screen
+///////////////////////////////////////////////////////////////////////////////
+
+// ClearRows blanks out every row of s from y1 to y2.
+func ClearRows(s tcell.Screen, y1 int, y2 int) {
+	width, _ := s.Size()
+	for row := y1; row <= y2; row++ {
+		for col := 0; col < width; col++ {
+			s.SetContent(col, row, ' ', nil, tcell.StyleDefault)
+		}
+	}
+}
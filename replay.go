@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Replay viewer
+///////////////////////////////////////////////////////////////////////////////
+
+// ReplayCommand runs the "replay" subcommand, which shows the game
+// in the move history file named by args one step at a time.
+// Returns the exit code for the program.
+func ReplayCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: replay <move history file>")
+		return 2
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	history, err := engine.ReadHistory(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read %s: %v\n", args[0], err)
+		return 1
+	}
+
+	s, err := NewScreen()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Screen initialization failed:", err)
+		return 1
+	}
+	defer FiniOnPanic(s)
+	// A broken config file shouldn't stop a replay, so it is only
+	// used for the theme if it can be read.
+	config, _ := LoadConfig()
+	UseTheme(s, config.Theme)
+	ReplayGame(s, history)
+	s.Fini()
+	return 0
+}
+
+// ReplayGame shows history on s, starting from the deal. The right
+// and left arrows step forwards and backwards through the actions,
+// Home and End jump to the start and end, and ESC leaves.
+func ReplayGame(s tcell.Screen, history engine.History) {
+	step := 0
+	for {
+		game := Wrap(history.GameAt(step))
+		game.message = fmt.Sprintf("Replay: step %d of %d", step, len(history.Actions))
+		if step > 0 {
+			game.message += ", " + history.Actions[step-1].ToString()
+		}
+		game.message += "  (left/right to step, ESC to leave)"
+		s.Clear()
+		game.Render(s, FitLayout(s.Size()))
+		s.Show()
+
+		switch ev := s.PollEvent().(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEscape:
+				return
+			case tcell.KeyRight:
+				if step < len(history.Actions) {
+					step++
+				}
+			case tcell.KeyLeft:
+				if step > 0 {
+					step--
+				}
+			case tcell.KeyHome:
+				step = 0
+			case tcell.KeyEnd:
+				step = len(history.Actions)
+			}
+		case *tcell.EventResize:
+			s.Sync()
+		}
+	}
+}
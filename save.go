@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"solitaire/engine"
+)
+
+// SAVE_VERSION is the version of the save file format. Files with
+// a different version are rejected.
+const SAVE_VERSION = 2
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// saveFile is the outer layer of a save file. Checksum is the
+// SHA-256 of State as compact JSON, so that files which have
+// been corrupted or edited by hand can be detected.
+type saveFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	State    json.RawMessage `json:"state"`
+}
+
+// savedGame is everything about a Game that is written to disk.
+// The undo history is not saved, but is rebuilt from the move
+// history when the game is loaded.
+type savedGame struct {
+	Difficulty  int           `json:"difficulty"`
+	Seed        int64         `json:"seed"`
+	Relaxed     bool          `json:"relaxed,omitempty"`
+	Manual      bool          `json:"manualCollect,omitempty"`
+	Moves       int           `json:"moves"`
+	Undos       int           `json:"undos"`
+	Elapsed     time.Duration `json:"elapsed"`
+	Stock       []savedCard   `json:"stock"`
+	Piles       []savedPile   `json:"piles"`
+	Highlighted savedSelected `json:"highlighted"`
+	ToMove      bool          `json:"toMove"`
+	Selected    savedSelected `json:"selected"`
+	History     []savedAction `json:"history"`
+}
+
+type savedPile struct {
+	Visible   []savedCard `json:"visible"`
+	Invisible []savedCard `json:"invisible"`
+}
+
+type savedCard struct {
+	Suit  int `json:"s"`
+	Value int `json:"v"`
+}
+
+type savedAction struct {
+	Kind    string `json:"kind"`
+	From    int    `json:"from,omitempty"`
+	Count   int    `json:"count,omitempty"`
+	To      int    `json:"to,omitempty"`
+	Cleared []int  `json:"cleared,omitempty"`
+}
+
+type savedSelected struct {
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	NumCards int `json:"n"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Saving and loading
+///////////////////////////////////////////////////////////////////////////////
+
+// ConfigPath returns the location of the file called name in the
+// directory where the game keeps its files.
+func ConfigPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "spider-solitaire", name), nil
+}
+
+// SavePath returns the location of the save file.
+func SavePath() (string, error) {
+	return ConfigPath("save.json")
+}
+
+// HasSave returns true iff there is a saved game to resume.
+func HasSave() bool {
+	path, err := SavePath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// DeleteSave removes the saved game, if there is one.
+func DeleteSave() {
+	if path, err := SavePath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// SaveGame writes game to the save file, replacing any game
+// that was saved before.
+func SaveGame(game Game) error {
+	path, err := SavePath()
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(game.toSaved())
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(saveFile{SAVE_VERSION, checksum(state), state}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return WriteConfigFile(path, data)
+}
+
+// WriteConfigFile replaces the file at path with data, creating the
+// directory it is in if needed. The data is written to a temporary
+// file first, so that a crash part way through never leaves a half
+// written file behind.
+func WriteConfigFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// LoadGame reads the game from the save file. It returns an error
+// describing the problem if the file is missing, from a different
+// version, corrupt, or does not describe a valid game.
+func LoadGame() (Game, error) {
+	var game Game
+	path, err := SavePath()
+	if err != nil {
+		return game, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return game, err
+	}
+
+	var file saveFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return game, errors.New("save file is corrupt")
+	}
+	if file.Version != SAVE_VERSION {
+		return game, fmt.Errorf("save file has version %d, but only version %d is supported",
+			file.Version, SAVE_VERSION)
+	}
+	// The state is indented when the file is written, so it is
+	// compacted again before checking it.
+	var state bytes.Buffer
+	if err := json.Compact(&state, file.State); err != nil ||
+		file.Checksum != checksum(state.Bytes()) {
+		return game, errors.New("save file is corrupt or has been modified")
+	}
+	var saved savedGame
+	if err := json.Unmarshal(file.State, &saved); err != nil {
+		return game, errors.New("save file is corrupt")
+	}
+	return saved.toGame()
+}
+
+// checksum returns the SHA-256 of data as a hex string.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Conversion between Game and savedGame
+///////////////////////////////////////////////////////////////////////////////
+
+// toSaved converts game into the form written to disk.
+func (game Game) toSaved() savedGame {
+	rules := game.Rules()
+	saved := savedGame{
+		Difficulty:  int(game.Difficulty()),
+		Seed:        game.Seed(),
+		Relaxed:     rules.Relaxed,
+		Manual:      rules.ManualCollect,
+		Moves:       game.Moves(),
+		Undos:       game.Undos(),
+		Elapsed:     game.Elapsed(),
+		Stock:       saveCards(game.Stock()),
+		Highlighted: savedSelected{game.highlighted.x, game.highlighted.y, game.highlighted.numCards},
+		ToMove:      game.toMove,
+		Selected:    savedSelected{game.selected.x, game.selected.y, game.selected.numCards},
+	}
+	for i := 0; i < engine.NUM_PILES; i++ {
+		pile := game.Pile(i)
+		saved.Piles = append(saved.Piles,
+			savedPile{saveCards(pile.Visible()), saveCards(pile.Hidden())})
+	}
+	for _, action := range game.History().Actions {
+		saved.History = append(saved.History, savedAction{string(action.Kind),
+			action.Move.From, action.Move.Count, action.Move.To, action.Cleared})
+	}
+	return saved
+}
+
+func saveCards(deck engine.Deck) []savedCard {
+	cards := make([]savedCard, 0, deck.Size())
+	for _, card := range deck.Cards() {
+		cards = append(cards, savedCard{int(card.Suit()), int(card.Value())})
+	}
+	return cards
+}
+
+// toGame converts a game read from disk back into a Game, checking
+// that it is a game that could actually have been played.
+func (saved savedGame) toGame() (Game, error) {
+	var game Game
+	difficulty := engine.Difficulty(saved.Difficulty)
+	if !difficulty.IsValid() {
+		return game, fmt.Errorf("save file has unknown difficulty %d", saved.Difficulty)
+	}
+	if saved.Seed <= 0 || saved.Moves < 0 || saved.Undos < 0 || saved.Elapsed < 0 {
+		return game, errors.New("save file has an invalid deal number, move count or time")
+	}
+	if len(saved.Piles) != engine.NUM_PILES {
+		return game, fmt.Errorf("save file has %d piles instead of %d",
+			len(saved.Piles), engine.NUM_PILES)
+	}
+
+	// The move history must lead from the deal to exactly the saved
+	// cards. Playing it again also rebuilds the undo history.
+	history := engine.History{
+		Difficulty: difficulty,
+		Seed:       saved.Seed,
+		Rules:      engine.Rules{Relaxed: saved.Relaxed, ManualCollect: saved.Manual},
+	}
+	for _, a := range saved.History {
+		history.Actions = append(history.Actions, engine.Action{Kind: engine.ActionKind(a.Kind),
+			Move: engine.Move{From: a.From, Count: a.Count, To: a.To}})
+	}
+	played, err := history.Replay()
+	if err != nil {
+		return game, errors.New("save file has a move history that can't be played")
+	}
+	same, err := sameCards(played.Stock(), saved.Stock)
+	if err != nil {
+		return game, err
+	}
+	for i, pile := range saved.Piles {
+		sameVisible, err := sameCards(played.Pile(i).Visible(), pile.Visible)
+		if err != nil {
+			return game, err
+		}
+		sameHidden, err := sameCards(played.Pile(i).Hidden(), pile.Invisible)
+		if err != nil {
+			return game, err
+		}
+		same = same && sameVisible && sameHidden
+	}
+	if !same {
+		return game, errors.New("save file has a move history that doesn't match its cards")
+	}
+	if played.Moves() != saved.Moves || played.Undos() != saved.Undos {
+		return game, errors.New("save file has a move count that doesn't match its move history")
+	}
+
+	game = Wrap(played)
+	game.elapsed = saved.Elapsed
+	game.highlighted = Selected{saved.Highlighted.X, saved.Highlighted.Y, saved.Highlighted.NumCards}
+	game.toMove = saved.ToMove
+	game.selected = Selected{saved.Selected.X, saved.Selected.Y, saved.Selected.NumCards}
+	if !game.isValidSelection(game.highlighted) ||
+		(game.toMove && !game.isValidSelection(game.selected)) {
+		return game, errors.New("save file has an invalid cursor or selection")
+	}
+	return game, nil
+}
+
+// sameCards returns true if deck has exactly the saved cards, in the
+// same order. Returns an error if any of the saved cards is invalid.
+func sameCards(deck engine.Deck, saved []savedCard) (bool, error) {
+	cards := deck.Cards()
+	same := len(cards) == len(saved)
+	for i, c := range saved {
+		card := engine.NewCard(engine.CardSuit(c.Suit), engine.CardValue(c.Value))
+		if !card.IsValid() {
+			return false, fmt.Errorf("save file has an invalid card (suit %d, value %d)",
+				c.Suit, c.Value)
+		}
+		same = same && card == cards[i]
+	}
+	return same, nil
+}
+
+// isValidSelection returns true iff sel points at the stock or at
+// cards that exist in one of the piles.
+func (game Game) isValidSelection(sel Selected) bool {
+	if sel.x < 0 || sel.x >= engine.NUM_PILES || sel.y < 0 || sel.y > 1 || sel.numCards < 1 {
+		return false
+	}
+	return sel.y == 0 || sel.numCards == 1 ||
+		sel.numCards <= game.Pile(sel.x).Visible().Size()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"solitaire/engine"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Command line
+///////////////////////////////////////////////////////////////////////////////
+
+// SolveCommand runs the "solve" subcommand with the arguments after
+// it, printing the result. Returns the exit code for the program.
+func SolveCommand(args []string) int {
+	flags := flag.NewFlagSet("solve", flag.ContinueOnError)
+	suits := flags.Int("suits", int(engine.FourSuits), "number of suits to play with (1, 2 or 4)")
+	seed := flags.Int64("seed", 0, "deal number to solve (0 for a random deal)")
+	fair := flags.Bool("fair", false, "only use the cards a player can see")
+	relaxed := flags.Bool("relaxed", false, "allow dealing from the stock while a pile is empty")
+	nodes := flags.Int("nodes", engine.DefaultSolveOptions.MaxNodes, "most positions to search")
+	limit := flags.Duration("time", engine.DefaultSolveOptions.MaxTime, "longest time to search for")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	difficulty := engine.Difficulty(*suits)
+	if !difficulty.IsValid() || *seed < 0 {
+		fmt.Fprintln(os.Stderr, "-suits must be 1, 2 or 4, and -seed must not be negative")
+		return 2
+	}
+	if *seed == 0 {
+		*seed = engine.NewSeed()
+	}
+
+	options := engine.SolveOptions{Mode: engine.Omniscient, MaxNodes: *nodes, MaxTime: *limit}
+	modeName := "omniscient"
+	if *fair {
+		options.Mode = engine.Fair
+		modeName = "fair"
+	}
+	game := engine.NewGame(difficulty, *seed, engine.Rules{Relaxed: *relaxed})
+	solution := engine.Solve(game, options)
+
+	fmt.Printf("Deal #%d, %s, %s mode\n", *seed, difficulty.ToString(), modeName)
+	switch solution.Result {
+	case engine.Solved:
+		fmt.Printf("Solved in %d moves", len(solution.Moves))
+	case engine.Unsolvable:
+		fmt.Print("Unsolvable")
+	case engine.GaveUp:
+		fmt.Print("Gave up")
+	}
+	fmt.Printf(" (%d positions, %v)\n", solution.Nodes, solution.Elapsed.Round(time.Millisecond))
+	for i, move := range solution.Moves {
+		fmt.Printf("%d. %s\n", i+1, move.ToString())
+	}
+	if solution.Result != engine.Solved {
+		return 1
+	}
+	return 0
+}
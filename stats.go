@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+// STATS_VERSION is the version of the stats file format.
+const STATS_VERSION = 1
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// GameResult is how a game ended.
+type GameResult string
+
+const (
+	Won       GameResult = "won"
+	Lost      GameResult = "lost" // there were no more moves
+	Abandoned GameResult = "abandoned"
+)
+
+// GameRecord is the result of one finished game.
+type GameRecord struct {
+	Result     GameResult    `json:"result"`
+	Difficulty int           `json:"difficulty"`
+	Seed       int64         `json:"seed"`
+	Moves      int           `json:"moves"`
+	Duration   time.Duration `json:"duration"`
+	Score      int           `json:"score"`
+	Finished   time.Time     `json:"finished"`
+}
+
+// statsFile is the format of the stats file.
+type statsFile struct {
+	Version int          `json:"version"`
+	Games   []GameRecord `json:"games"`
+}
+
+// Stats is a summary of every game played at one difficulty.
+type Stats struct {
+	played        int
+	won           int
+	streak        int // wins in a row up to the latest game
+	longestStreak int
+	fastestWin    time.Duration // zero if no games have been won
+	fewestMoves   int           // zero if no games have been won
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Recording games
+///////////////////////////////////////////////////////////////////////////////
+
+// StatsPath returns the location of the stats file.
+func StatsPath() (string, error) {
+	return ConfigPath("stats.json")
+}
+
+// LoadRecords returns every game recorded in the stats file, oldest
+// first. A missing stats file has no games in it.
+func LoadRecords() ([]GameRecord, error) {
+	path, err := StatsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var file statsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.New("stats file is corrupt")
+	}
+	if file.Version != STATS_VERSION {
+		return nil, fmt.Errorf("stats file has version %d, but only version %d is supported",
+			file.Version, STATS_VERSION)
+	}
+	return file.Games, nil
+}
+
+// RecordGame adds game to the stats file with the given result.
+// Games where the player never made a move are not recorded.
+func RecordGame(game Game, result GameResult) error {
+	if game.Moves() == 0 {
+		return nil
+	}
+	records, err := LoadRecords()
+	if err != nil {
+		return err
+	}
+	records = append(records, GameRecord{
+		Result:     result,
+		Difficulty: int(game.Difficulty()),
+		Seed:       game.Seed(),
+		Moves:      game.Moves(),
+		Duration:   game.Elapsed(),
+		Score:      game.Score(),
+		Finished:   time.Now(),
+	})
+
+	path, err := StatsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(statsFile{STATS_VERSION, records}, "", "\t")
+	if err != nil {
+		return err
+	}
+	return WriteConfigFile(path, data)
+}
+
+// AbandonSave records the saved game, if there is one, as abandoned
+// and deletes it. This is used when the player starts a new game
+// instead of resuming the one they left with ESC.
+func AbandonSave() error {
+	if !HasSave() {
+		return nil
+	}
+	saved, err := LoadGame()
+	DeleteSave()
+	if err != nil {
+		// A save that can't be read can't be counted either.
+		return nil
+	}
+	return RecordGame(saved, Abandoned)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Summarising games
+///////////////////////////////////////////////////////////////////////////////
+
+// SummariseRecords returns the Stats of the games in records which
+// were played at difficulty. records must be oldest first.
+func SummariseRecords(records []GameRecord, difficulty engine.Difficulty) Stats {
+	var stats Stats
+	for _, record := range records {
+		if engine.Difficulty(record.Difficulty) != difficulty {
+			continue
+		}
+		stats.played++
+		if record.Result != Won {
+			stats.streak = 0
+			continue
+		}
+		stats.won++
+		stats.streak++
+		if stats.streak > stats.longestStreak {
+			stats.longestStreak = stats.streak
+		}
+		if stats.fastestWin == 0 || record.Duration < stats.fastestWin {
+			stats.fastestWin = record.Duration
+		}
+		if stats.fewestMoves == 0 || record.Moves < stats.fewestMoves {
+			stats.fewestMoves = record.Moves
+		}
+	}
+	return stats
+}
+
+// WinRate returns the percentage of games played that were won.
+func (stats Stats) WinRate() int {
+	if stats.played == 0 {
+		return 0
+	}
+	return stats.won * 100 / stats.played
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Graphics
+///////////////////////////////////////////////////////////////////////////////
+
+// StatsScreen shows the player's stats for each difficulty until
+// they press a key.
+func StatsScreen(s tcell.Screen) {
+	s.Clear()
+	emitStr(s, 5, 0, 200, 0, tcell.StyleDefault.Bold(true), "Statistics")
+	records, err := LoadRecords()
+	if err != nil {
+		emitStr(s, 5, 2, 200, 2, CurrentTheme.error,
+			"Could not load stats: "+err.Error())
+	} else {
+		emitStr(s, 5, 2, 200, 2, tcell.StyleDefault.Bold(true), fmt.Sprintf(
+			"%-11s %7s %5s %5s %7s %12s %9s %8s", "", "Played", "Won", "Win%",
+			"Streak", "Best streak", "Fastest", "Fewest"))
+		for i, difficulty := range []engine.Difficulty{engine.OneSuit, engine.TwoSuits, engine.FourSuits} {
+			stats := SummariseRecords(records, difficulty)
+			fastest, fewest := "-", "-"
+			if stats.won > 0 {
+				fastest = stats.fastestWin.Round(time.Second).String()
+				fewest = fmt.Sprint(stats.fewestMoves)
+			}
+			emitStr(s, 5, 3+i, 200, 3+i, tcell.StyleDefault, fmt.Sprintf(
+				"%-11s %7d %5d %4d%% %7d %12d %9s %8s", difficulty.ToString(),
+				stats.played, stats.won, stats.WinRate(), stats.streak,
+				stats.longestStreak, fastest, fewest))
+		}
+	}
+	emitStr(s, 5, 7, 200, 7, tcell.StyleDefault, "Press any key to go back")
+	s.Show()
+
+	for {
+		if _, ok := s.PollEvent().(*tcell.EventKey); ok {
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+// TICK_INTERVAL is how often the screen is redrawn while a game is
+// being played, so that the clock keeps up.
+const TICK_INTERVAL = time.Second
+
+// IDLE_PAUSE is how long the player can leave a game without
+// pressing a key or using the mouse before it is paused. tcell
+// can't tell when the terminal loses focus, so this stands in for
+// pausing when the player switches to another window.
+const IDLE_PAUSE = 2 * time.Minute
+
+///////////////////////////////////////////////////////////////////////////////
+// Clock
+///////////////////////////////////////////////////////////////////////////////
+
+// Tick wakes up the event loop reading from s every interval, by
+// posting an EventInterrupt, until stop is closed.
+func Tick(s tcell.Screen, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.PostEvent(tcell.NewEventInterrupt(nil))
+		case <-stop:
+			return
+		}
+	}
+}
+
+// FormatDuration returns d as minutes and seconds, with the hours
+// in front once it is an hour or longer.
+func FormatDuration(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Graphics
+///////////////////////////////////////////////////////////////////////////////
+
+// RenderStatus renders the status bar next to the stock, showing
+// the time played, the moves made, the deals left, how many suits
+// have been completed, the difficulty, the deal number and the
+// score. If there isn't room, a shorter form is shown, which keeps
+// the score and the deal number.
+func (game Game) RenderStatus(s tcell.Screen, layout Layout) {
+	clock := FormatDuration(game.Elapsed())
+	paused := ""
+	if game.paused {
+		paused = " (paused)"
+	}
+	forms := []string{
+		fmt.Sprintf("Time %s%s | Moves %d | Deals left %d | Completed %d of %d | %s | Deal #%d | Score %d",
+			clock, paused, game.Moves(), game.DealsLeft(), game.CompletedStacks(), engine.NUM_STACKS,
+			game.Difficulty().ToString(), game.Seed(), game.Score()),
+		fmt.Sprintf("%s%s | Moves %d | Deals %d | Done %d/%d | Score %d | #%d",
+			clock, paused, game.Moves(), game.DealsLeft(), game.CompletedStacks(), engine.NUM_STACKS,
+			game.Score(), game.Seed()),
+		// The deals left are on the stock, and the box over the piles
+		// shows that the game is paused.
+		fmt.Sprintf("%s | %d moves | %d/%d done | Score %d | #%d",
+			clock, game.Moves(), game.CompletedStacks(), engine.NUM_STACKS,
+			game.Score(), game.Seed()),
+	}
+	status := forms[len(forms)-1]
+	for _, form := range forms {
+		if len(form) < layout.Right()-layout.InfoX() {
+			status = form
+			break
+		}
+	}
+	style := CurrentTheme.status
+	for x := layout.InfoX(); x <= layout.Right(); x++ {
+		s.SetContent(x, layout.y, ' ', nil, style)
+	}
+	emitStr(s, layout.InfoX()+1, layout.y, layout.Right(), layout.y, style, status)
+}
+
+// RenderPaused renders a box over the piles while game is paused,
+// so that the cards can't be studied with the clock stopped.
+func RenderPaused(s tcell.Screen, layout Layout) {
+	style := CurrentTheme.paused
+	box := Box{s, layout.x, layout.PilesTop(), layout.Right(), layout.PilesTop() + layout.height,
+		style, "Paused. Press any key to carry on, or ESC to save and leave", false}
+	box.Draw()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// StuckChoice is what the player chooses to do once there are no
+// more moves.
+type StuckChoice int
+
+const (
+	StuckUndo    StuckChoice = iota // take back the last action and keep playing
+	StuckRestart                    // play the same deal again
+	StuckNewDeal                    // play a new random deal
+	StuckLeave                      // quit the program
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// No more moves
+///////////////////////////////////////////////////////////////////////////////
+
+// AskWhenStuck shows the player that game has no more moves, and
+// waits for them to choose what to do next.
+func AskWhenStuck(s tcell.Screen, game Game) StuckChoice {
+	for {
+		RenderStuck(s, FitLayout(s.Size()), game)
+		s.Show()
+
+		switch ev := s.PollEvent().(type) {
+		case *tcell.EventKey:
+			// As in the game, ESC always leaves. Undo uses the player's
+			// keys, which win over r and n if they clash.
+			if ev.Key() == tcell.KeyEscape {
+				return StuckLeave
+			}
+			if CurrentKeys.Lookup(ev).action == ActionUndo {
+				return StuckUndo
+			}
+			switch ev.Key() {
+			case tcell.KeyRune:
+				switch ev.Rune() {
+				case 'r':
+					return StuckRestart
+				case 'n':
+					return StuckNewDeal
+				}
+			}
+		case *tcell.EventResize:
+			s.Sync()
+		}
+	}
+}
+
+// RenderStuck renders the message that game has no more moves, with
+// the choices the player has, over the top of the game drawn with
+// layout.
+func RenderStuck(s tcell.Screen, layout Layout, game Game) {
+	style := CurrentTheme.stuck
+	x, y, x2 := layout.x, layout.y, layout.Right()
+	box := Box{s, x, y, x2, y + 7, style, "No more moves", false}
+	box.Draw()
+	emitStr(s, x+1, y+2, x2-1, y+2, style, fmt.Sprintf(
+		"Nothing can be moved or dealt in deal #%d (score %d, %d moves)",
+		game.Seed(), game.Score(), game.Moves()))
+	emitStr(s, x+1, y+4, x2-1, y+4, style,
+		"Press "+CurrentKeys.Describe(ActionUndo)+" to undo your last action")
+	emitStr(s, x+1, y+5, x2-1, y+5, style, "Press r to restart this deal, or n for a new deal")
+	emitStr(s, x+1, y+6, x2-1, y+6, style, "Press ESC to leave")
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"github.com/gdamore/tcell"
+
+	"solitaire/engine"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// Data Types
+///////////////////////////////////////////////////////////////////////////////
+
+// Theme is the set of styles the game is drawn with.
+type Theme struct {
+	name        string
+	face        tcell.Style    // the edges of face up cards
+	suits       [5]tcell.Style // the labels on face up cards, by suit
+	back        tcell.Style    // the edges of face down cards, and the stock
+	backPattern tcell.Style    // the pattern on face down cards
+	highlight   tcell.Style    // the box around the cards under the cursor
+	selected    tcell.Style    // the box around the cards the player picked up
+	hint        tcell.Style    // the boxes showing a hint
+	status      tcell.Style    // the status bar
+	won         tcell.Style    // the message that the game was won
+	stuck       tcell.Style    // the message that there are no more moves
+	paused      tcell.Style    // the box over the piles while paused
+	error       tcell.Style    // messages about things going wrong
+}
+
+// suitStyles returns the styles for the labels of each suit, with
+// black for the black suits and red for the red suits, on bg.
+func suitStyles(bg tcell.Color, black tcell.Color, red tcell.Color) [5]tcell.Style {
+	style := tcell.StyleDefault.Background(bg).Bold(true)
+	return [5]tcell.Style{
+		engine.NoneSuit: style.Foreground(black),
+		engine.Spades:   style.Foreground(black),
+		engine.Hearts:   style.Foreground(red),
+		engine.Clubs:    style.Foreground(black),
+		engine.Diamonds: style.Foreground(red),
+	}
+}
+
+// ClassicTheme has white cards with navy backs.
+var ClassicTheme = Theme{
+	name:        "classic",
+	face:        tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorWhite),
+	suits:       suitStyles(tcell.ColorWhite, tcell.ColorBlack, tcell.ColorRed),
+	back:        tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+	backPattern: tcell.StyleDefault.Foreground(tcell.ColorBlue).Background(tcell.ColorNavy),
+	highlight:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorFuchsia),
+	selected:    tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorYellow),
+	hint:        tcell.StyleDefault.Foreground(tcell.ColorAqua).Background(tcell.ColorAqua),
+	status:      tcell.StyleDefault.Reverse(true),
+	won:         tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorGreen),
+	stuck:       tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorMaroon),
+	paused:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+	error:       tcell.StyleDefault.Foreground(tcell.ColorRed),
+}
+
+// DarkTheme has dark cards with light labels, for dark terminals.
+var DarkTheme = Theme{
+	name:        "dark",
+	face:        tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorBlack),
+	suits:       suitStyles(tcell.ColorBlack, tcell.ColorSilver, tcell.ColorRed),
+	back:        tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorMaroon),
+	backPattern: tcell.StyleDefault.Foreground(tcell.ColorPurple).Background(tcell.ColorMaroon),
+	highlight:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorTeal),
+	selected:    tcell.StyleDefault.Foreground(tcell.ColorOlive).Background(tcell.ColorOlive),
+	hint:        tcell.StyleDefault.Foreground(tcell.ColorNavy).Background(tcell.ColorNavy),
+	status:      tcell.StyleDefault.Foreground(tcell.ColorSilver).Background(tcell.ColorNavy),
+	won:         tcell.StyleDefault.Foreground(tcell.ColorSilver).Background(tcell.ColorGreen),
+	stuck:       tcell.StyleDefault.Foreground(tcell.ColorSilver).Background(tcell.ColorMaroon),
+	paused:      tcell.StyleDefault.Foreground(tcell.ColorSilver).Background(tcell.ColorNavy),
+	error:       tcell.StyleDefault.Foreground(tcell.ColorRed),
+}
+
+// HighContrastTheme uses only black, white and the brightest colours.
+var HighContrastTheme = Theme{
+	name:        "high-contrast",
+	face:        tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+	suits:       suitStyles(tcell.ColorWhite, tcell.ColorBlack, tcell.ColorRed),
+	back:        tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+	backPattern: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+	highlight:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow),
+	selected:    tcell.StyleDefault.Foreground(tcell.ColorLime).Background(tcell.ColorLime),
+	hint:        tcell.StyleDefault.Foreground(tcell.ColorAqua).Background(tcell.ColorAqua),
+	status:      tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+	won:         tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorLime).Bold(true),
+	stuck:       tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow).Bold(true),
+	paused:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Bold(true),
+	error:       tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true),
+}
+
+// MonochromeTheme uses no colours at all, only bold, underlined and
+// reversed text. The red suits are underlined.
+var MonochromeTheme = Theme{
+	name: "monochrome",
+	face: tcell.StyleDefault,
+	suits: [5]tcell.Style{
+		engine.NoneSuit: tcell.StyleDefault.Bold(true),
+		engine.Spades:   tcell.StyleDefault.Bold(true),
+		engine.Hearts:   tcell.StyleDefault.Bold(true).Underline(true),
+		engine.Clubs:    tcell.StyleDefault.Bold(true),
+		engine.Diamonds: tcell.StyleDefault.Bold(true).Underline(true),
+	},
+	back:        tcell.StyleDefault.Reverse(true),
+	backPattern: tcell.StyleDefault,
+	highlight:   tcell.StyleDefault.Reverse(true),
+	selected:    tcell.StyleDefault.Reverse(true).Bold(true).Blink(true),
+	hint:        tcell.StyleDefault.Bold(true),
+	status:      tcell.StyleDefault.Reverse(true),
+	won:         tcell.StyleDefault.Reverse(true),
+	stuck:       tcell.StyleDefault.Reverse(true),
+	paused:      tcell.StyleDefault.Reverse(true),
+	error:       tcell.StyleDefault.Bold(true),
+}
+
+// ColourBlindTheme gives each suit its own colour, from a palette
+// that can be told apart with the common kinds of colour blindness,
+// and doesn't rely on telling red from green anywhere else.
+var ColourBlindTheme = Theme{
+	name: "colour-blind",
+	face: tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorWhite),
+	suits: [5]tcell.Style{
+		engine.NoneSuit: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+		engine.Spades:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+		engine.Hearts:   tcell.StyleDefault.Foreground(tcell.NewHexColor(0xD55E00)).Background(tcell.ColorWhite).Bold(true),
+		engine.Clubs:    tcell.StyleDefault.Foreground(tcell.NewHexColor(0x009E73)).Background(tcell.ColorWhite).Bold(true),
+		engine.Diamonds: tcell.StyleDefault.Foreground(tcell.NewHexColor(0x0072B2)).Background(tcell.ColorWhite).Bold(true),
+	},
+	back:        tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+	backPattern: tcell.StyleDefault.Foreground(tcell.ColorBlue).Background(tcell.ColorNavy),
+	highlight:   tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.NewHexColor(0xE69F00)),
+	selected:    tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorYellow),
+	hint:        tcell.StyleDefault.Foreground(tcell.NewHexColor(0x56B4E9)).Background(tcell.NewHexColor(0x56B4E9)),
+	status:      tcell.StyleDefault.Reverse(true),
+	won:         tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.NewHexColor(0x0072B2)),
+	stuck:       tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.NewHexColor(0xE69F00)),
+	paused:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+	error:       tcell.StyleDefault.Foreground(tcell.NewHexColor(0xD55E00)).Bold(true),
+}
+
+// Themes are the themes the player can choose from, with the
+// default first.
+var Themes = []Theme{ClassicTheme, DarkTheme, HighContrastTheme, MonochromeTheme, ColourBlindTheme}
+
+// CurrentTheme is the theme everything is drawn with. It is set with
+// UseTheme.
+var CurrentTheme = ClassicTheme
+
+///////////////////////////////////////////////////////////////////////////////
+// Theme functions
+///////////////////////////////////////////////////////////////////////////////
+
+// FindTheme returns the theme called name, or false if there is no
+// such theme.
+func FindTheme(name string) (Theme, bool) {
+	for _, theme := range Themes {
+		if theme.name == name {
+			return theme, true
+		}
+	}
+	return Theme{}, false
+}
+
+// NextThemeName returns the name of the theme after the one called
+// name, going back to the first theme after the last.
+func NextThemeName(name string) string {
+	for i, theme := range Themes {
+		if theme.name == name {
+			return Themes[(i+1)%len(Themes)].name
+		}
+	}
+	return Themes[0].name
+}
+
+// UseTheme draws everything on s with the theme called name, or the
+// default theme if there isn't one called name, changed to suit the
+// number of colours s can show.
+func UseTheme(s tcell.Screen, name string) {
+	theme, ok := FindTheme(name)
+	if !ok {
+		theme = Themes[0]
+	}
+	CurrentTheme = theme.ForColors(s.Colors())
+}
+
+// ForColors returns theme changed for a terminal that can show n
+// colours. Terminals with fewer than 256 colours get the nearest
+// colours they have, and terminals with fewer than 8 colours get
+// the monochrome theme.
+func (theme Theme) ForColors(n int) Theme {
+	if n >= 256 {
+		return theme
+	}
+	if n < 8 {
+		mono := MonochromeTheme
+		mono.name = theme.name
+		return mono
+	}
+	palette := make([]tcell.Color, n)
+	for i := range palette {
+		palette[i] = tcell.Color(i)
+	}
+	fit := theme
+	fit.face = fitStyle(theme.face, palette, true)
+	for suit, style := range theme.suits {
+		fit.suits[suit] = fitStyle(style, palette, true)
+	}
+	fit.back = fitStyle(theme.back, palette, true)
+	fit.backPattern = fitStyle(theme.backPattern, palette, false)
+	fit.highlight = fitStyle(theme.highlight, palette, false)
+	fit.selected = fitStyle(theme.selected, palette, false)
+	fit.hint = fitStyle(theme.hint, palette, false)
+	fit.status = fitStyle(theme.status, palette, true)
+	fit.won = fitStyle(theme.won, palette, true)
+	fit.stuck = fitStyle(theme.stuck, palette, true)
+	fit.paused = fitStyle(theme.paused, palette, true)
+	fit.error = fitStyle(theme.error, palette, true)
+	return fit
+}
+
+// fitStyle returns style with its colours changed to the nearest
+// ones in palette. If readable is true and the text would end up
+// the same colour as its background, the text is made the darkest
+// or lightest colour in palette instead.
+func fitStyle(style tcell.Style, palette []tcell.Color, readable bool) tcell.Style {
+	fg, bg, attrs := style.Decompose()
+	if fg != tcell.ColorDefault {
+		fg = tcell.FindColor(fg, palette)
+	}
+	if bg != tcell.ColorDefault {
+		bg = tcell.FindColor(bg, palette)
+	}
+	if readable && fg == bg && fg != tcell.ColorDefault {
+		fg = palette[0]
+		if bg == palette[0] {
+			fg = palette[len(palette)-1]
+		}
+	}
+	return tcell.StyleDefault.Foreground(fg).Background(bg).
+		Bold(attrs&tcell.AttrBold != 0).
+		Blink(attrs&tcell.AttrBlink != 0).
+		Dim(attrs&tcell.AttrDim != 0).
+		Italic(attrs&tcell.AttrItalic != 0).
+		Reverse(attrs&tcell.AttrReverse != 0).
+		Underline(attrs&tcell.AttrUnderline != 0)
+}